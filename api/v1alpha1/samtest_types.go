@@ -17,13 +17,433 @@ limitations under the License.
 package v1alpha1
 
 import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// DeletionPolicy controls what happens to the managed resources when a
+// Samtest is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete tears every managed resource down in reverse
+	// dependency order, waiting for the pods of the Deployment to terminate.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan releases every managed resource, leaving them
+	// running in the cluster without an owner.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+	// DeletionPolicyRetain tears the workload down but releases resources
+	// holding state which outlives it, such as the address of the Service.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// ResourceProfile names a set of compute resources configured on the operator.
+// +kubebuilder:validation:Enum=small;medium;large
+type ResourceProfile string
+
+const (
+	ResourceProfileSmall  ResourceProfile = "small"
+	ResourceProfileMedium ResourceProfile = "medium"
+	ResourceProfileLarge  ResourceProfile = "large"
+)
+
+// ServiceType is how the Service of a Samtest is exposed.
+// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer;Headless
+type ServiceType string
+
+const (
+	ServiceTypeClusterIP    ServiceType = "ClusterIP"
+	ServiceTypeNodePort     ServiceType = "NodePort"
+	ServiceTypeLoadBalancer ServiceType = "LoadBalancer"
+	// ServiceTypeHeadless renders a Service without a cluster IP, resolving
+	// directly to the addresses of the pods.
+	ServiceTypeHeadless ServiceType = "Headless"
+)
+
+// ServiceSpec configures the Service published for a Samtest.
+// +kubebuilder:validation:XValidation:rule="!has(self.externalTrafficPolicy) || self.type in ['NodePort', 'LoadBalancer']",message="externalTrafficPolicy requires a NodePort or LoadBalancer Service"
+// +kubebuilder:validation:XValidation:rule="!has(self.loadBalancerSourceRanges) || self.type == 'LoadBalancer'",message="loadBalancerSourceRanges requires a LoadBalancer Service"
+type ServiceSpec struct {
+	// Disabled stops a Service being published for the Samtest, deleting
+	// any which was published before.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// +kubebuilder:default:=ClusterIP
+	Type ServiceType `json:"type,omitempty"`
+
+	// Annotations are added to the Service, for example to configure a
+	// cloud load balancer.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// ExternalTrafficPolicy controls whether external traffic is routed to
+	// node-local endpoints only, preserving the client source address.
+	// +optional
+	// +kubebuilder:validation:Enum=Cluster;Local
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicy `json:"externalTrafficPolicy,omitempty"`
+
+	// SessionAffinity pins the connections of a client to a single pod when
+	// set to ClientIP.
+	// +optional
+	// +kubebuilder:validation:Enum=None;ClientIP
+	SessionAffinity corev1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+
+	// LoadBalancerSourceRanges restricts the client CIDRs allowed through
+	// the load balancer.
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+}
+
+// IngressPath is a path routed to the Service of a Samtest.
+type IngressPath struct {
+	// +kubebuilder:default:=/
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path,omitempty"`
+
+	// +kubebuilder:default:=Prefix
+	// +kubebuilder:validation:Enum=Exact;Prefix;ImplementationSpecific
+	PathType networkingv1.PathType `json:"pathType,omitempty"`
+}
+
+// IngressSpec configures the Ingress published for a Samtest.
+type IngressSpec struct {
+	// IngressClassName selects the ingress controller serving the Ingress,
+	// falling back to the default class of the cluster.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// Annotations are added to the Ingress, for example to configure the
+	// ingress controller.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Hosts are the hostnames routed to the Samtest. When empty, requests
+	// for any host are routed.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=32
+	Hosts []string `json:"hosts,omitempty"`
+
+	// Paths are routed on every host. When empty, every path is routed.
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	Paths []IngressPath `json:"paths,omitempty"`
+
+	// Port is the name of the port in ports which traffic is routed to.
+	// +kubebuilder:default:=http
+	Port string `json:"port,omitempty"`
+
+	// TLSSecretName is the name of the Secret holding the certificate used
+	// to terminate TLS for every host.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// RouteParentRef references a Gateway the HTTPRoute of a Samtest attaches to.
+type RouteParentRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the Gateway, defaulting to the namespace of the Samtest.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// SectionName selects a single listener of the Gateway.
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// RoutePathMatch matches requests by their path.
+type RoutePathMatch struct {
+	// +kubebuilder:default:=PathPrefix
+	// +kubebuilder:validation:Enum=Exact;PathPrefix;RegularExpression
+	Type string `json:"type,omitempty"`
+
+	// +kubebuilder:default:=/
+	// +kubebuilder:validation:Pattern=`^/`
+	Value string `json:"value,omitempty"`
+}
+
+// RouteBackendRef is a Service requests are split across by weight.
+// +kubebuilder:validation:XValidation:rule="!has(self.name) || has(self.port)",message="port is required when naming a Service"
+type RouteBackendRef struct {
+	// Name of a Service in the namespace of the Samtest, defaulting to the
+	// Service of the Samtest.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Port of the named Service. The Service of the Samtest is always
+	// reached on the port named by the route.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+
+	// Weight is the share of requests sent to the Service, relative to the
+	// other backends.
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000000
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// RouteSpec configures the Gateway API HTTPRoute published for a Samtest.
+type RouteSpec struct {
+	// ParentRefs are the Gateways the HTTPRoute attaches to.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=32
+	ParentRefs []RouteParentRef `json:"parentRefs"`
+
+	// Hostnames are matched against the Host header of requests. When empty,
+	// the hostnames of the Gateway listeners are used.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Matches select the requests routed. When empty, every request is.
+	// +optional
+	// +kubebuilder:validation:MaxItems=8
+	Matches []RoutePathMatch `json:"matches,omitempty"`
+
+	// BackendRefs split requests across Services by weight. When empty,
+	// every request is sent to the Service of the Samtest.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	BackendRefs []RouteBackendRef `json:"backendRefs,omitempty"`
+
+	// Port is the name of the port in ports which traffic for the Service of
+	// the Samtest is routed to.
+	// +kubebuilder:default:=http
+	Port string `json:"port,omitempty"`
+}
+
+// RouteParentStatus reflects the conditions a Gateway reported for the
+// HTTPRoute of a Samtest.
+type RouteParentStatus struct {
+	Name       string             `json:"name"`
+	Namespace  string             `json:"namespace,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// AutoscalingSpec configures a HorizontalPodAutoscaler scaling the Deployment
+// of a Samtest.
+// +kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas must not exceed maxReplicas"
+type AutoscalingSpec struct {
+	// +optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// TargetCPUUtilizationPercentage is the average CPU utilisation of the
+	// pods, relative to their requests, which the autoscaler aims for.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// TargetMemoryUtilizationPercentage is the average memory utilisation of
+	// the pods, relative to their requests, which the autoscaler aims for.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+
+	// Metrics are additional metrics, such as custom or external metrics,
+	// the autoscaler scales on. Without any metrics or utilisation targets,
+	// the autoscaler targets 80% CPU utilisation.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+}
+
+// AutoscalingStatus is the state of the HorizontalPodAutoscaler of a Samtest.
+type AutoscalingStatus struct {
+	// CurrentReplicas is the number of replicas last seen by the autoscaler.
+	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+
+	// DesiredReplicas is the number of replicas last calculated by the
+	// autoscaler.
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+}
+
+// DisruptionBudgetSpec configures the PodDisruptionBudget protecting the pods
+// of a Samtest from voluntary disruptions, such as node drains.
+// +kubebuilder:validation:XValidation:rule="has(self.minAvailable) != has(self.maxUnavailable)",message="exactly one of minAvailable or maxUnavailable must be set"
+type DisruptionBudgetSpec struct {
+	// MinAvailable is the number or percentage of pods which must remain
+	// available during a disruption.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of pods which may be
+	// unavailable during a disruption.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// SecurityContextSpec overrides the security context defaults of a Samtest,
+// which comply with the restricted Pod Security Standard. Only the fields set
+// here replace the defaults.
+type SecurityContextSpec struct {
+	// Pod overrides fields of the security context of the pods.
+	// +optional
+	Pod *corev1.PodSecurityContext `json:"pod,omitempty"`
+
+	// Container overrides fields of the security context of the main
+	// container.
+	// +optional
+	Container *corev1.SecurityContext `json:"container,omitempty"`
+}
+
+// ServiceAccountSpec configures the ServiceAccount the pods of a Samtest run
+// as. A ServiceAccount is generated for each Samtest unless an existing one is
+// named.
+// +kubebuilder:validation:XValidation:rule="!has(self.name) || !has(self.annotations)",message="annotations can only be set on a generated ServiceAccount"
+type ServiceAccountSpec struct {
+	// Name references an existing ServiceAccount in the namespace of the
+	// Samtest, which is used instead of generating one.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Annotations are set on the generated ServiceAccount, such as those
+	// binding it to a cloud IAM role for workload identity.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// AutomountServiceAccountToken controls whether the API token of the
+	// ServiceAccount is mounted into the pods. Unset leaves the Kubernetes
+	// default, which mounts the token.
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+}
+
+// RBACSpec grants the ServiceAccount of a Samtest permissions within its
+// namespace, through a Role and a RoleBinding.
+type RBACSpec struct {
+	// Rules are the permissions granted. Rules granting more than the
+	// ceiling configured on the operator are refused.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:XValidation:rule="self.all(r, has(r.apiGroups) && has(r.resources))",message="rules must set apiGroups and resources"
+	// +kubebuilder:validation:XValidation:rule="self.all(r, !has(r.nonResourceURLs))",message="nonResourceURLs cannot be granted by a namespaced Role"
+	Rules []rbacv1.PolicyRule `json:"rules"`
+}
+
+// Volume is a volume which can be mounted into the main container. Only the
+// sources allowed by the restricted Pod Security Standard are supported.
+// +kubebuilder:validation:XValidation:rule="[has(self.configMap), has(self.secret), has(self.emptyDir), has(self.projected), has(self.csi)].filter(s, s).size() == 1",message="exactly one volume source must be set"
+type Volume struct {
+	// Name identifies the volume to the volume mounts.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// +optional
+	ConfigMap *corev1.ConfigMapVolumeSource `json:"configMap,omitempty"`
+
+	// +optional
+	Secret *corev1.SecretVolumeSource `json:"secret,omitempty"`
+
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// +optional
+	Projected *corev1.ProjectedVolumeSource `json:"projected,omitempty"`
+
+	// +optional
+	CSI *corev1.CSIVolumeSource `json:"csi,omitempty"`
+}
+
+// PersistenceSpec configures a PersistentVolumeClaim mounted into the main
+// container. Removing persistence from a Samtest releases the claim rather
+// than deleting it, so its data is never lost by accident.
+// +kubebuilder:validation:XValidation:rule="quantity(string(self.size)).compareTo(quantity(string(oldSelf.size))) >= 0",message="size can only be expanded"
+// +kubebuilder:validation:XValidation:rule="has(oldSelf.storageClassName) == has(self.storageClassName)",message="storageClassName is immutable"
+type PersistenceSpec struct {
+	// Size is the storage requested for the claim. It can only be expanded,
+	// which requires a storage class allowing volume expansion.
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName is the storage class of the claim, defaulting to the
+	// default storage class of the cluster.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="storageClassName is immutable"
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// AccessModes of the claim. A ReadWriteOnce or ReadWriteOncePod claim can
+	// only be mounted by a single replica, which is replaced rather than
+	// rolled on update so the old pod releases the claim first.
+	// +optional
+	// +kubebuilder:default:={"ReadWriteOnce"}
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=4
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="accessModes are immutable"
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+
+	// MountPath is where the claim is mounted in the main container.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=255
+	MountPath string `json:"mountPath"`
+
+	// RetainOnDelete releases the claim rather than deleting it when the
+	// Samtest is deleted, whatever its deletion policy.
+	// +optional
+	RetainOnDelete bool `json:"retainOnDelete,omitempty"`
+}
+
+// Port is a port exposed by the main container and published on the Service.
+type Port struct {
+	// Name identifies the port on the container and the Service, and is how
+	// probes and routes refer to it.
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// ContainerPort is the port the main container listens on.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ContainerPort int32 `json:"containerPort"`
+
+	// +kubebuilder:default:=TCP
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+
+	// ServicePort is the port published on the Service, defaulting to the
+	// container port.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ServicePort int32 `json:"servicePort,omitempty"`
+
+	// AppProtocol is the application protocol of the port, such as http,
+	// https or kubernetes.io/h2c.
+	// +optional
+	AppProtocol *string `json:"appProtocol,omitempty"`
+}
+
 // SamtestSpec defines the desired state of Samtest.
+// +kubebuilder:validation:XValidation:rule="!has(self.livenessProbe) || ((!has(self.livenessProbe.httpGet) || type(self.livenessProbe.httpGet.port) == int || (has(self.ports) ? self.ports.exists(p, p.name == self.livenessProbe.httpGet.port) : self.livenessProbe.httpGet.port == 'http')) && (!has(self.livenessProbe.tcpSocket) || type(self.livenessProbe.tcpSocket.port) == int || (has(self.ports) ? self.ports.exists(p, p.name == self.livenessProbe.tcpSocket.port) : self.livenessProbe.tcpSocket.port == 'http')))",message="livenessProbe must reference a port by number or by a name listed in ports"
+// +kubebuilder:validation:XValidation:rule="!has(self.readinessProbe) || ((!has(self.readinessProbe.httpGet) || type(self.readinessProbe.httpGet.port) == int || (has(self.ports) ? self.ports.exists(p, p.name == self.readinessProbe.httpGet.port) : self.readinessProbe.httpGet.port == 'http')) && (!has(self.readinessProbe.tcpSocket) || type(self.readinessProbe.tcpSocket.port) == int || (has(self.ports) ? self.ports.exists(p, p.name == self.readinessProbe.tcpSocket.port) : self.readinessProbe.tcpSocket.port == 'http')))",message="readinessProbe must reference a port by number or by a name listed in ports"
+// +kubebuilder:validation:XValidation:rule="!has(self.startupProbe) || ((!has(self.startupProbe.httpGet) || type(self.startupProbe.httpGet.port) == int || (has(self.ports) ? self.ports.exists(p, p.name == self.startupProbe.httpGet.port) : self.startupProbe.httpGet.port == 'http')) && (!has(self.startupProbe.tcpSocket) || type(self.startupProbe.tcpSocket.port) == int || (has(self.ports) ? self.ports.exists(p, p.name == self.startupProbe.tcpSocket.port) : self.startupProbe.tcpSocket.port == 'http')))",message="startupProbe must reference a port by number or by a name listed in ports"
+// +kubebuilder:validation:XValidation:rule="!has(self.ingress) || (has(self.ports) ? self.ports.exists(p, p.name == self.ingress.port) : self.ingress.port == 'http')",message="ingress must reference a port by a name listed in ports"
+// +kubebuilder:validation:XValidation:rule="!has(self.ingress) || !has(self.service) || !self.service.disabled",message="ingress requires the Service to be enabled"
+// +kubebuilder:validation:XValidation:rule="!has(self.route) || (has(self.ports) ? self.ports.exists(p, p.name == self.route.port) : self.route.port == 'http')",message="route must reference a port by a name listed in ports"
+// +kubebuilder:validation:XValidation:rule="!has(self.route) || !has(self.service) || !self.service.disabled",message="route requires the Service to be enabled"
+// +kubebuilder:validation:XValidation:rule="!has(self.volumeMounts) || has(self.volumes) && self.volumeMounts.all(m, self.volumes.exists(v, v.name == m.name))",message="volumeMounts must reference a volume listed in volumes"
+// +kubebuilder:validation:XValidation:rule="!has(self.persistence) || !self.persistence.accessModes.exists(m, m == 'ReadWriteOnce' || m == 'ReadWriteOncePod') || (self.replicas <= 1 && !has(self.autoscaling))",message="a ReadWriteOnce or ReadWriteOncePod persistence claim requires a single replica without autoscaling"
+// +kubebuilder:validation:XValidation:rule="!has(self.persistence) || !has(self.volumeMounts) || self.volumeMounts.all(m, m.mountPath != self.persistence.mountPath)",message="volumeMounts must not use the mountPath of persistence"
 type SamtestSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
@@ -35,8 +455,168 @@ type SamtestSpec struct {
 	// +kubebuilder:validation:Required
 	Image string `json:"image"`
 
+	// Replicas is the number of pods run by the Deployment. It is ignored
+	// whilst autoscaling is enabled.
 	// +kubebuilder:validation:Required
-	Repliacas int `json:"replicas"`
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
+
+	// +kubebuilder:default:=Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// ResourceProfile selects a named set of compute resources for the main
+	// container, as configured on the operator.
+	// +optional
+	ResourceProfile ResourceProfile `json:"resourceProfile,omitempty"`
+
+	// Resources are the compute resources of the main container. Any request
+	// or limit set here takes precedence over the resource profile.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Env are the environment variables of the main container, set from
+	// literal values, keys of Secrets or ConfigMaps, or the downward API.
+	// Names prefixed with SAMTEST_ are reserved for variables injected by the
+	// operator.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=256
+	// +kubebuilder:validation:XValidation:rule="self.all(e, !e.name.startsWith('SAMTEST_'))",message="env names prefixed with SAMTEST_ are reserved by the operator"
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// EnvFrom populates environment variables of the main container from
+	// every key of a Secret or ConfigMap. Variables set in env take
+	// precedence.
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	// +kubebuilder:validation:XValidation:rule="self.all(e, !has(e.prefix) || !e.prefix.startsWith('SAMTEST_'))",message="envFrom prefixes starting with SAMTEST_ are reserved by the operator"
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// Ports are exposed by the main container and published on the Service.
+	// When empty, a single port named http is exposed on port 80.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:XValidation:rule="self.all(p, self.exists_one(q, q.containerPort == p.containerPort && q.protocol == p.protocol))",message="container ports must be unique per protocol"
+	// +kubebuilder:validation:XValidation:rule="self.all(p, self.exists_one(q, (has(q.servicePort) ? q.servicePort : q.containerPort) == (has(p.servicePort) ? p.servicePort : p.containerPort) && q.protocol == p.protocol))",message="service ports must be unique per protocol"
+	Ports []Port `json:"ports,omitempty"`
+
+	// LivenessProbe restarts the main container when it fails. HTTP and TCP
+	// probes can reference a port by its name in ports.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+
+	// ReadinessProbe removes the pod from the Service endpoints when it
+	// fails. When unset and a port named http exists, the pod is probed with
+	// an HTTP GET of / on that port.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	// StartupProbe holds off the other probes until the main container has
+	// started.
+	// +optional
+	StartupProbe *corev1.Probe `json:"startupProbe,omitempty"`
+
+	// Service configures the Service published for the Samtest. When unset,
+	// a ClusterIP Service is published.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+
+	// Ingress publishes the Service through an Ingress when set.
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// Route publishes the Service through a Gateway API HTTPRoute when set.
+	// Requires the Gateway API CRDs to be installed.
+	// +optional
+	Route *RouteSpec `json:"route,omitempty"`
+
+	// Autoscaling scales the Deployment with a HorizontalPodAutoscaler when
+	// set, in place of the replicas field.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// DisruptionBudget limits voluntary disruptions of the pods when set. It
+	// is skipped whilst the Samtest may run a single replica, where any
+	// budget would block node drains.
+	// +optional
+	DisruptionBudget *DisruptionBudgetSpec `json:"disruptionBudget,omitempty"`
+
+	// NodeSelector restricts the pods to nodes with every listed label.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations allow the pods onto nodes with matching taints.
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity constrains the nodes the pods are scheduled onto, relative to
+	// node labels or to other pods.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints control how the pods are spread across
+	// topology domains. They take precedence over the default spread for
+	// the same topology key.
+	// +optional
+	// +kubebuilder:validation:MaxItems=8
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// SpreadReplicas spreads the pods evenly across zones and nodes where
+	// possible, without blocking scheduling when it is not.
+	// +optional
+	SpreadReplicas bool `json:"spreadReplicas,omitempty"`
+
+	// PriorityClassName sets the scheduling priority of the pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// SecurityContext overrides the security context defaults, which run
+	// the main container as a non-root user with a read-only root
+	// filesystem, no capabilities, no privilege escalation and the runtime
+	// default seccomp profile.
+	// +optional
+	SecurityContext *SecurityContextSpec `json:"securityContext,omitempty"`
+
+	// ServiceAccount configures the ServiceAccount the pods run as. Unset
+	// generates a ServiceAccount named after the Samtest.
+	// +optional
+	ServiceAccount *ServiceAccountSpec `json:"serviceAccount,omitempty"`
+
+	// RBAC grants the ServiceAccount of the pods permissions within the
+	// namespace of the Samtest.
+	// +optional
+	RBAC *RBACSpec `json:"rbac,omitempty"`
+
+	// Volumes can be mounted into the main container through volumeMounts.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:XValidation:rule="self.all(v, v.name != 'data')",message="the volume name data is reserved for persistence"
+	Volumes []Volume `json:"volumes,omitempty"`
+
+	// VolumeMounts mount the volumes into the main container.
+	// +optional
+	// +listType=map
+	// +listMapKey=mountPath
+	// +kubebuilder:validation:MaxItems=32
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// Persistence mounts a PersistentVolumeClaim managed by the operator into
+	// the main container.
+	// +optional
+	Persistence *PersistenceSpec `json:"persistence,omitempty"`
+}
+
+// ResourceReference identifies a resource created for a Samtest.
+type ResourceReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
 }
 
 // SamtestStatus defines the observed state of Samtest.
@@ -44,10 +624,74 @@ type SamtestStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the Samtest most recently
+	// acted upon by the reconciler.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastReconcileTime is the time the reconciler last changed the status.
+	// Passes which leave the status unchanged do not update it.
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// Replicas is the number of pods currently targeted by the Deployment,
+	// as reported through the scale subresource.
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector for the pods of the Deployment, used
+	// by the scale subresource to let autoscalers find the pods.
+	Selector string `json:"selector,omitempty"`
+
+	// ReadyReplicas is the number of pods of the Deployment which are ready.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// AvailableReplicas is the number of pods of the Deployment which have
+	// been ready for at least the minimum ready duration.
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// Image is the image currently deployed by the Deployment.
+	Image string `json:"image,omitempty"`
+
+	// QOSClass is the quality of service class the pods of the Deployment are
+	// intended to run with, determined from the compute resources rendered for
+	// the main container. It is not read from the pods, so it does not reflect
+	// containers or resources injected by admission webhooks.
+	QOSClass corev1.PodQOSClass `json:"qosClass,omitempty"`
+
+	// ClusterIP is the cluster IP allocated to the Service.
+	ClusterIP string `json:"clusterIP,omitempty"`
+
+	// DNSName is the fully qualified in-cluster DNS name of the Service, in
+	// the cluster domain configured on the operator.
+	DNSName string `json:"dnsName,omitempty"`
+
+	// LoadBalancerAddresses are the IPs or hostnames assigned to the
+	// load balancer of a LoadBalancer Service.
+	LoadBalancerAddresses []string `json:"loadBalancerAddresses,omitempty"`
+
+	// IngressAddresses are the IPs or hostnames assigned to the Ingress by
+	// the ingress controller.
+	IngressAddresses []string `json:"ingressAddresses,omitempty"`
+
+	// RouteParents are the conditions reported by each Gateway the HTTPRoute
+	// attaches to, such as whether the route was accepted.
+	RouteParents []RouteParentStatus `json:"routeParents,omitempty"`
+
+	// Autoscaling is the state of the HorizontalPodAutoscaler, whilst
+	// autoscaling is enabled.
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// Inventory lists every resource created for the Samtest, so resources
+	// which are no longer rendered can be pruned.
+	Inventory []ResourceReference `json:"inventory,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Image",type="string",JSONPath=".status.image"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Samtest is the Schema for the samtests API.
 type Samtest struct {
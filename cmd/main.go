@@ -38,6 +38,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	operatorconfig "github.com/s-humphreys/go-operator-sdk/internal/config"
 	"github.com/s-humphreys/go-operator-sdk/internal/controller"
 	// +kubebuilder:scaffold:imports
 )
@@ -61,6 +62,7 @@ func main() {
 	var webhookCertPath, webhookCertName, webhookCertKey string
 	var enableLeaderElection bool
 	var probeAddr string
+	var configPath string
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
@@ -81,6 +83,8 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&configPath, "config", "",
+		"The path to the operator configuration file. If unset, the default configuration is used.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -202,10 +206,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	operatorConfig := operatorconfig.Default()
+	if len(configPath) > 0 {
+		setupLog.Info("Loading operator configuration", "config", configPath)
+		operatorConfig, err = operatorconfig.Load(configPath)
+		if err != nil {
+			setupLog.Error(err, "unable to load operator configuration")
+			os.Exit(1)
+		}
+	}
+
 	if err := (&controller.SamtestReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("samtest-controller"),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("samtest-controller"),
+		Config:    operatorConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Samtest")
 		os.Exit(1)
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// Config is the operator-level configuration, shared by every Samtest.
+type Config struct {
+	// ResourceProfiles maps the name of a resource profile to the compute
+	// resources it grants the main container.
+	ResourceProfiles map[string]corev1.ResourceRequirements `json:"resourceProfiles,omitempty"`
+
+	// RBACCeiling is the most a Samtest may grant its ServiceAccount through
+	// spec.rbac.rules. Rules granting anything the ceiling does not are
+	// refused. The operator must itself hold every permission of the ceiling,
+	// as the API server refuses Roles granting more than their creator holds,
+	// so a ceiling wider than the default needs the ClusterRole of the
+	// operator widened to match.
+	RBACCeiling []rbacv1.PolicyRule `json:"rbacCeiling,omitempty"`
+
+	// ClusterDomain is the DNS domain of the cluster, used to report the
+	// fully qualified DNS name of each Service.
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+}
+
+// Default returns the configuration used when no configuration file is provided.
+func Default() Config {
+	return Config{
+		ResourceProfiles: map[string]corev1.ResourceRequirements{
+			"small":  newProfile("100m", "128Mi", "256Mi"),
+			"medium": newProfile("250m", "256Mi", "512Mi"),
+			"large":  newProfile("500m", "512Mi", "1Gi"),
+		},
+		// Reading configuration, which the ClusterRole of the operator grants
+		RBACCeiling: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"configmaps"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+		},
+		ClusterDomain: "cluster.local",
+	}
+}
+
+// Load reads the configuration from a YAML file. Anything not set in the file
+// falls back to the default configuration.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	loaded := Config{}
+	if err := yaml.UnmarshalStrict(data, &loaded); err != nil {
+		return cfg, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	for name, profile := range loaded.ResourceProfiles {
+		cfg.ResourceProfiles[name] = profile
+	}
+	if loaded.RBACCeiling != nil {
+		cfg.RBACCeiling = loaded.RBACCeiling
+	}
+	if loaded.ClusterDomain != "" {
+		cfg.ClusterDomain = loaded.ClusterDomain
+	}
+
+	return cfg, nil
+}
+
+// Creates a resource profile requesting CPU and memory, limiting only memory so
+// the container can burst into spare CPU.
+func newProfile(cpuRequest string, memoryRequest string, memoryLimit string) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpuRequest),
+			corev1.ResourceMemory: resource.MustParse(memoryRequest),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse(memoryLimit),
+		},
+	}
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+)
+
+// Field indexes of the Samtests by the names of the ConfigMaps and Secrets they
+// reference, so a change to either can be mapped back to the Samtests using it.
+const (
+	configMapIndexKey = ".spec.configMapRefs"
+	secretIndexKey    = ".spec.secretRefs"
+)
+
+// Returns the sorted names of the ConfigMaps referenced by the Samtest.
+func referencedConfigMaps(samtest *cachev1alpha1.Samtest) []string {
+	names := []string{}
+	for _, envVar := range samtest.Spec.Env {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.ConfigMapKeyRef != nil {
+			names = append(names, envVar.ValueFrom.ConfigMapKeyRef.Name)
+		}
+	}
+	for _, source := range samtest.Spec.EnvFrom {
+		if source.ConfigMapRef != nil {
+			names = append(names, source.ConfigMapRef.Name)
+		}
+	}
+	for _, volume := range samtest.Spec.Volumes {
+		if volume.ConfigMap != nil {
+			names = append(names, volume.ConfigMap.Name)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					names = append(names, source.ConfigMap.Name)
+				}
+			}
+		}
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Returns the sorted names of the Secrets referenced by the Samtest.
+func referencedSecrets(samtest *cachev1alpha1.Samtest) []string {
+	names := []string{}
+	for _, envVar := range samtest.Spec.Env {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+			names = append(names, envVar.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+	for _, source := range samtest.Spec.EnvFrom {
+		if source.SecretRef != nil {
+			names = append(names, source.SecretRef.Name)
+		}
+	}
+	for _, volume := range samtest.Spec.Volumes {
+		if volume.Secret != nil {
+			names = append(names, volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					names = append(names, source.Secret.Name)
+				}
+			}
+		}
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Hashes the data of every ConfigMap and Secret referenced by the Samtest, so a
+// change to any of them changes the pod template and rolls the Deployment.
+// Returns an empty checksum when nothing is referenced. The data is read from
+// the API server, as only the metadata of ConfigMaps and Secrets is cached.
+func (r *SamtestReconciler) configChecksum(ctx context.Context, samtest *cachev1alpha1.Samtest) (string, error) {
+	configMaps := referencedConfigMaps(samtest)
+	secrets := referencedSecrets(samtest)
+	if len(configMaps) == 0 && len(secrets) == 0 {
+		return "", nil
+	}
+
+	h := sha256.New()
+	for _, name := range configMaps {
+		configMap := &corev1.ConfigMap{}
+		key := types.NamespacedName{Name: name, Namespace: samtest.Namespace}
+		if err := r.APIReader.Get(ctx, key, configMap); err != nil {
+			if !errors.IsNotFound(err) {
+				return "", err
+			}
+			// A missing ConfigMap is hashed too, so creating it rolls the pods
+			fmt.Fprintf(h, "ConfigMap/%s missing\n", name)
+			continue
+		}
+
+		fmt.Fprintf(h, "ConfigMap/%s\n", name)
+		hashData(h, configMap.Data)
+		hashBinaryData(h, configMap.BinaryData)
+	}
+
+	for _, name := range secrets {
+		secret := &corev1.Secret{}
+		key := types.NamespacedName{Name: name, Namespace: samtest.Namespace}
+		if err := r.APIReader.Get(ctx, key, secret); err != nil {
+			if !errors.IsNotFound(err) {
+				return "", err
+			}
+			fmt.Fprintf(h, "Secret/%s missing\n", name)
+			continue
+		}
+
+		fmt.Fprintf(h, "Secret/%s\n", name)
+		hashBinaryData(h, secret.Data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Writes string data to the hash in key order, as map iteration is random.
+func hashData(h hash.Hash, data map[string]string) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%q\n", key, data[key])
+	}
+}
+
+// Writes binary data to the hash in key order, as map iteration is random.
+func hashBinaryData(h hash.Hash, data map[string][]byte) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%x\n", key, data[key])
+	}
+}
+
+// Maps a ConfigMap to reconcile requests for the Samtests referencing it.
+func (r *SamtestReconciler) samtestsForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.samtestsReferencing(ctx, configMapIndexKey, obj)
+}
+
+// Maps a Secret to reconcile requests for the Samtests referencing it.
+func (r *SamtestReconciler) samtestsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.samtestsReferencing(ctx, secretIndexKey, obj)
+}
+
+// Lists the Samtests in the namespace of the object which reference it through
+// the provided field index.
+func (r *SamtestReconciler) samtestsReferencing(ctx context.Context, indexKey string, obj client.Object) []reconcile.Request {
+	samtests := &cachev1alpha1.SamtestList{}
+	if err := r.List(ctx, samtests,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{indexKey: obj.GetName()},
+	); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(samtests.Items))
+	for _, samtest := range samtests.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&samtest)})
+	}
+	return requests
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s/resources"
+)
+
+// samtestFinalizer blocks deletion of a Samtest until its managed resources
+// have been torn down according to its deletion policy.
+const samtestFinalizer = "cache.k8s.capitalontap.com/finalizer"
+
+// teardownRequeueInterval is how often teardown progress is checked whilst
+// waiting on resources or pods which are still terminating.
+const teardownRequeueInterval = 5 * time.Second
+
+// Tears down the resources recorded in the inventory of the Samtest in reverse
+// dependency order, removing the finalizer once every resource has been
+// deleted or released.
+func (r *SamtestReconciler) finalize(ctx context.Context, samtest *cachev1alpha1.Samtest) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(samtest, samtestFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	policy := samtest.Spec.DeletionPolicy
+	if policy == "" {
+		policy = cachev1alpha1.DeletionPolicyDelete
+	}
+
+	for _, target := range r.teardownTargets(samtest) {
+		done, progress, err := r.teardownResource(log, ctx, samtest, target, policy)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if !done {
+			log.Info("waiting on teardown", "progress", progress)
+			if err := r.updateStatus(ctx, samtest, k8s.TearingDown, progress); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: teardownRequeueInterval}, nil
+		}
+	}
+
+	log.Info("teardown complete, removing finalizer", "deletionPolicy", policy)
+	controllerutil.RemoveFinalizer(samtest, samtestFinalizer)
+	if err := r.Update(ctx, samtest); err != nil {
+		log.Error(err, "failed to remove finalizer")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// A resource to tear down, along with the renderer of its kind. The renderer
+// is nil for a kind the operator no longer renders.
+type teardownTarget struct {
+	ref      cachev1alpha1.ResourceReference
+	resource resources.Resource
+	order    int
+	// Whether the resource is no longer desired by the spec of the Samtest
+	stale bool
+}
+
+// Lists the resources to tear down in reverse dependency order. These are
+// taken from the inventory rather than the spec, so a resource dropped from
+// the spec since it was applied is torn down too. The renderers only decide
+// the order and how each kind is released, and a kind none of them renders is
+// torn down first.
+func (r *SamtestReconciler) teardownTargets(samtest *cachev1alpha1.Samtest) []teardownTarget {
+	prototypes := []resources.Resource{
+		&resources.ServiceAccount{},
+		&resources.Role{},
+		&resources.RoleBinding{},
+		&resources.PersistentVolumeClaim{},
+		&resources.Deployment{ResourceProfiles: r.Config.ResourceProfiles},
+		&resources.HorizontalPodAutoscaler{},
+		&resources.PodDisruptionBudget{},
+		&resources.Service{ClusterDomain: r.Config.ClusterDomain},
+		&resources.Ingress{},
+		&resources.HTTPRoute{},
+	}
+	renderers := map[schema.GroupVersionKind]teardownTarget{}
+	for i, prototype := range prototypes {
+		resource := prototype.New(samtest)
+		gvk := resource.Generate().GetObjectKind().GroupVersionKind()
+		renderers[gvk] = teardownTarget{resource: resource, order: i}
+	}
+
+	// Resources desired by the spec are included in case the inventory was
+	// never recorded, such as when the first pass failed part way
+	desired := newInventory(r.managedResources(samtest, ""))
+	refs := slices.Clone(samtest.Status.Inventory)
+	for _, ref := range desired {
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+
+	targets := make([]teardownTarget, 0, len(refs))
+	for _, ref := range refs {
+		target, ok := renderers[schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)]
+		if !ok {
+			target.order = len(prototypes)
+		}
+		target.ref = ref
+		target.stale = !slices.Contains(desired, ref)
+		targets = append(targets, target)
+	}
+	slices.SortStableFunc(targets, func(a, b teardownTarget) int {
+		return cmp.Compare(b.order, a.order)
+	})
+	return targets
+}
+
+// Deletes or releases a single managed resource. Returns whether the resource
+// is fully torn down, and if not a message describing what is being waited on.
+func (r *SamtestReconciler) teardownResource(
+	log logr.Logger,
+	ctx context.Context,
+	crd *cachev1alpha1.Samtest,
+	target teardownTarget,
+	policy cachev1alpha1.DeletionPolicy,
+) (bool, string, error) {
+	kind, name := target.ref.Kind, target.ref.Name
+
+	foundObj := &unstructured.Unstructured{}
+	foundObj.SetGroupVersionKind(schema.FromAPIVersionAndKind(target.ref.APIVersion, kind))
+	if err := r.Get(ctx, client.ObjectKey{Namespace: crd.Namespace, Name: name}, foundObj); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			if target.resource == nil {
+				return true, "", nil
+			}
+			return r.waitForPods(ctx, target.resource.Generate())
+		}
+		return false, "", err
+	}
+
+	// Never touch resources which are not managed by this Samtest
+	if !metav1.IsControlledBy(foundObj, crd) {
+		return true, "", nil
+	}
+
+	// Claims dropped from the spec are released, as pruning would have done
+	retainable, ok := target.resource.(resources.Retainable)
+	if policy == cachev1alpha1.DeletionPolicyOrphan || (ok && retainable.Retained(policy)) ||
+		(target.stale && kind == "PersistentVolumeClaim") {
+		log.Info("releasing resource", "kind", kind, "name", name)
+		if err := r.releaseResource(ctx, crd, foundObj); err != nil {
+			log.Error(err, "failed to release resource", "kind", kind)
+			return false, "", err
+		}
+		k8s.NewOrphanedEvent(crd, r.Recorder, kind, name)
+		return true, "", nil
+	}
+
+	if foundObj.GetDeletionTimestamp().IsZero() {
+		log.Info("deleting resource", "kind", kind, "name", name)
+		err := r.Delete(ctx, foundObj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "failed to delete resource", "kind", kind)
+			k8s.NewDeleteErrorEvent(crd, r.Recorder, kind, name)
+			return false, "", err
+		}
+		k8s.NewDeletedEvent(crd, r.Recorder, kind, name)
+	}
+
+	return false, fmt.Sprintf("Waiting for %s %s to be deleted", kind, name), nil
+}
+
+// Removes the controller owner reference of the Samtest from a resource so it
+// is not garbage collected alongside the Samtest.
+func (r *SamtestReconciler) releaseResource(ctx context.Context, crd *cachev1alpha1.Samtest, obj client.Object) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	ownerRefs := []metav1.OwnerReference{}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID != crd.UID {
+			ownerRefs = append(ownerRefs, ref)
+		}
+	}
+	obj.SetOwnerReferences(ownerRefs)
+
+	return r.Patch(ctx, obj, patch)
+}
+
+// Waits for the pods of a deleted Deployment to terminate, so the workload is
+// fully gone before the resources it depends on are torn down.
+func (r *SamtestReconciler) waitForPods(ctx context.Context, obj client.Object) (bool, string, error) {
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return true, "", nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods,
+		client.InNamespace(deployment.Namespace),
+		client.MatchingLabels(deployment.Spec.Selector.MatchLabels),
+	); err != nil {
+		return false, "", err
+	}
+
+	if len(pods.Items) > 0 {
+		return false, fmt.Sprintf("Waiting for %d pods of Deployment %s to terminate", len(pods.Items), deployment.Name), nil
+	}
+
+	return true, "", nil
+}
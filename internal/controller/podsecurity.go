@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	psaapi "k8s.io/pod-security-admission/api"
+	"k8s.io/pod-security-admission/policy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s/resources"
+)
+
+// The evaluator used by Pod Security Admission, built once from its checks.
+var podSecurityEvaluator = sync.OnceValues(func() (policy.Evaluator, error) {
+	return policy.NewEvaluator(policy.DefaultChecks())
+})
+
+// Checks the pods rendered for the Samtest against the Pod Security Standard
+// enforced on its namespace, returning the level and version evaluated and
+// every violation. Pod Security Admission would otherwise accept the
+// Deployment but reject each of its pods, which only shows on the ReplicaSet.
+// The zero LevelVersion is returned when the namespace has no enforce label,
+// as there is nothing to check the pods against.
+//
+// The namespace is read from the API server rather than the cache, so the
+// controller does not hold an informer over every namespace in the cluster.
+func (r *SamtestReconciler) checkPodSecurity(
+	ctx context.Context,
+	namespace string,
+	managedResources []resources.Resource,
+) (psaapi.LevelVersion, []string, error) {
+	evaluator, err := podSecurityEvaluator()
+	if err != nil {
+		return psaapi.LevelVersion{}, nil, err
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.APIReader.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return psaapi.LevelVersion{}, nil, err
+	}
+	if _, ok := ns.Labels[psaapi.EnforceLevelLabel]; !ok {
+		return psaapi.LevelVersion{}, nil, nil
+	}
+	// Labels which fail to parse are evaluated as restricted at the latest
+	// version, as Pod Security Admission does
+	enforce := psaapi.LevelVersion{Level: psaapi.LevelPrivileged, Version: psaapi.LatestVersion()}
+	nsPolicy, _ := psaapi.PolicyToEvaluate(ns.Labels, psaapi.Policy{Enforce: enforce, Audit: enforce, Warn: enforce})
+
+	var violations []string
+	for _, resource := range managedResources {
+		deployment, ok := resource.Generate().(*appsv1.Deployment)
+		if !ok {
+			continue
+		}
+		template := deployment.Spec.Template
+		for _, result := range evaluator.EvaluatePod(nsPolicy.Enforce, &template.ObjectMeta, &template.Spec) {
+			if !result.Allowed {
+				violations = append(violations, fmt.Sprintf("%s (%s)", result.ForbiddenReason, result.ForbiddenDetail))
+			}
+		}
+	}
+	return nsPolicy.Enforce, violations, nil
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s/resources"
+)
+
+// Builds the inventory of the resources currently managed for the Samtest.
+func newInventory(managedResources []resources.Resource) []cachev1alpha1.ResourceReference {
+	inventory := make([]cachev1alpha1.ResourceReference, 0, len(managedResources))
+	for _, resource := range managedResources {
+		obj := resource.Generate()
+		apiVersion, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+		inventory = append(inventory, cachev1alpha1.ResourceReference{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Name:       obj.GetName(),
+		})
+	}
+	return inventory
+}
+
+// Deletes resources recorded in the previous inventory which are no longer
+// managed for the Samtest. Only resources controlled by the Samtest are
+// deleted, anything else found under a stale reference is left alone.
+func (r *SamtestReconciler) prune(
+	log logr.Logger,
+	ctx context.Context,
+	crd *cachev1alpha1.Samtest,
+	inventory []cachev1alpha1.ResourceReference,
+) error {
+	current := map[cachev1alpha1.ResourceReference]bool{}
+	for _, ref := range inventory {
+		current[ref] = true
+	}
+
+	for _, ref := range crd.Status.Inventory {
+		if current[ref] {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		if err := r.Get(ctx, client.ObjectKey{Namespace: crd.Namespace, Name: ref.Name}, obj); err != nil {
+			// A kind which is no longer served, such as after its CRD was
+			// uninstalled, leaves nothing behind to prune
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return err
+		}
+
+		if !metav1.IsControlledBy(obj, crd) {
+			log.Info("stale resource is not controlled by this resource, skipping prune", "kind", ref.Kind, "name", ref.Name)
+			continue
+		}
+
+		// Claims hold data, so one dropped from the Samtest is released
+		// rather than deleted and left for its owner to clean up
+		if ref.Kind == "PersistentVolumeClaim" {
+			log.Info("releasing stale resource", "kind", ref.Kind, "name", ref.Name)
+			if err := r.releaseResource(ctx, crd, obj); err != nil {
+				log.Error(err, "failed to release resource", "kind", ref.Kind)
+				return err
+			}
+			k8s.NewOrphanedEvent(crd, r.Recorder, ref.Kind, ref.Name)
+			continue
+		}
+
+		log.Info("pruning stale resource", "kind", ref.Kind, "name", ref.Name)
+		err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "failed to prune resource", "kind", ref.Kind)
+			k8s.NewDeleteErrorEvent(crd, r.Recorder, ref.Kind, ref.Name)
+			return err
+		}
+		k8s.NewDeletedEvent(crd, r.Recorder, ref.Kind, ref.Name)
+	}
+
+	return nil
+}
@@ -2,6 +2,8 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"slices"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -14,6 +16,11 @@ import (
 	"github.com/s-humphreys/go-operator-sdk/internal/k8s/resources"
 )
 
+// FieldManager is the field manager used when server-side applying managed
+// resources. The operator only owns the fields it renders, so fields set by
+// other controllers are left alone.
+const FieldManager = "samtest-controller"
+
 func (r *SamtestReconciler) reconcileResource(
 	log logr.Logger,
 	ctx context.Context,
@@ -25,68 +32,173 @@ func (r *SamtestReconciler) reconcileResource(
 	name := desiredObj.GetName()
 	log.Info("reconciling resource", "kind", kind, "name", name)
 
+	// Set owner references, adopting the resource if it already exists
+	if err := ctrl.SetControllerReference(crd, desiredObj, r.Scheme); err != nil {
+		log.Error(err, "failed to set controller reference", "kind", kind)
+		return ctrl.Result{}, err
+	}
+
 	foundObj := desiredObj.DeepCopyObject().(client.Object)
 	err := r.Get(ctx, client.ObjectKeyFromObject(desiredObj), foundObj)
+	exists := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
 
-	if errors.IsNotFound(err) {
-		log.Info("resource not found, creating", "kind", kind)
+	// Resources with immutable fields which have drifted are deleted, and
+	// recreated once gone
+	if replaceable, ok := resource.(resources.Replaceable); ok && exists &&
+		metav1.IsControlledBy(foundObj, crd) && replaceable.RequiresReplacement(foundObj) {
+		return ctrl.Result{}, r.replaceResource(log, ctx, crd, kind, foundObj)
+	}
 
-		// Set owner references
-		if err := ctrl.SetControllerReference(crd, foundObj, r.Scheme); err != nil {
-			log.Error(err, "failed to set controller reference")
-			return ctrl.Result{}, err
-		}
+	// The resource is always applied, as the equality check cannot see fields
+	// which were removed from the Samtest. An apply which changes nothing is a
+	// no-op on the API server, so the check only decides what is reported.
+	switch {
+	case !exists:
+		log.Info("resource not found, applying", "kind", kind, "name", name)
+	case !resource.IsEqual(foundObj) || !metav1.IsControlledBy(foundObj, crd):
+		log.Info("resource is out of sync, applying", "kind", kind, "name", name)
+		k8s.NewOutOfSyncEvent(crd, r.Recorder, kind, name)
+	}
+
+	if preserving, ok := resource.(resources.Preserving); ok && exists {
+		preserving.Preserve(desiredObj, foundObj, FieldManager)
+	}
 
-		// Create the resource
-		if err := r.Create(ctx, foundObj); err != nil {
+	applyObj, err := resources.NewApplyConfiguration(desiredObj)
+	if err != nil {
+		log.Error(err, "failed to build apply configuration", "kind", kind)
+		return ctrl.Result{}, err
+	}
+
+	err = r.Patch(ctx, applyObj, client.Apply, client.FieldOwner(FieldManager))
+	if errors.IsConflict(err) && reclaimable(resource, err) {
+		log.Info("reclaiming fields from other field managers", "kind", kind, "name", name)
+		err = r.Patch(ctx, applyObj, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership)
+	}
+	if err != nil {
+		switch {
+		case errors.IsConflict(err):
+			log.Error(err, "field ownership conflict applying resource", "kind", kind)
+			k8s.NewConflictEvent(crd, r.Recorder, kind, name, err)
+		case exists:
+			log.Error(err, "failed to update resource", "kind", kind)
+			k8s.NewUpdateErrorEvent(crd, r.Recorder, kind, name)
+		default:
 			log.Error(err, "failed to create resource", "kind", kind)
 			k8s.NewCreateErrorEvent(crd, r.Recorder, kind, name)
-			return ctrl.Result{}, err
 		}
+		return ctrl.Result{}, err
+	}
 
+	// A changed resource version means the apply changed the resource
+	if exists {
+		if applyObj.GetResourceVersion() != foundObj.GetResourceVersion() {
+			k8s.NewUpdatedEvent(crd, r.Recorder, kind, name)
+			log.Info("resource updated", "kind", kind)
+		}
+	} else {
 		k8s.NewCreatedEvent(crd, r.Recorder, kind, name)
 		log.Info("resource created", "kind", kind)
-	} else if err != nil {
-		return ctrl.Result{}, err
 	}
 
-	// Perform equality check & update if different
-	// if !resource.IsEqual(foundObj) {
-	// 	log.Info("resource is out of sync, updating", "kind", kind, "name", name)
-	// 	k8s.NewOutOfSyncEvent(crd, r.Recorder, kind, name)
-
-	// 	switch desired := desiredObj.(type) {
-	// 	case *appsv1.Deployment:
-	// 		found := foundObj.(*appsv1.Deployment)
-	// 		found.Spec = desired.Spec
-	// 	case *corev1.Service:
-	// 		found := foundObj.(*corev1.Service)
-	// 		// For services, we must preserve the ClusterIP
-	// 		clusterIP := found.Spec.ClusterIP
-	// 		found.Spec = desired.Spec
-	// 		found.Spec.ClusterIP = clusterIP
-	// 	}
-
-	// 	if err := r.Update(ctx, foundObj); err != nil {
-	// 		log.Error(err, "failed to update resource")
-	// 		k8s.NewUpdateErrorEvent(crd, r.Recorder, kind, name)
-	// 		return ctrl.Result{}, err
-	// 	}
-	// 	k8s.NewUpdatedEvent(crd, r.Recorder, kind, name)
-	// }
-
-	// If resource is not managed by this controller, set the owner reference
-	if !metav1.IsControlledBy(foundObj, crd) {
-		log.Info("resource not managed, setting owner reference", "kind", kind)
-		if err := ctrl.SetControllerReference(crd, foundObj, r.Scheme); err != nil {
-			log.Error(err, "failed to set controller reference on existing Deployment")
-			return ctrl.Result{}, err
-		}
-		if err := r.Update(ctx, foundObj); err != nil {
-			log.Error(err, "failed to update resource with owner reference", "kind", kind)
-			return ctrl.Result{}, err
+	return ctrl.Result{}, nil
+}
+
+// Reports whether every field of an apply conflict is one the resource takes
+// back from other field managers.
+func reclaimable(resource resources.Resource, err error) bool {
+	fields, ok := resource.(resources.Reclaimable)
+	if !ok {
+		return false
+	}
+
+	status, ok := err.(errors.APIStatus)
+	if !ok || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return false
+	}
+
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict || !slices.Contains(fields.ReclaimableFields(), cause.Field) {
+			return false
 		}
 	}
+	return true
+}
 
-	return ctrl.Result{}, nil
+// Deletes a resource which cannot be updated in place. The resource is applied
+// again on a later pass, once the deletion has completed.
+func (r *SamtestReconciler) replaceResource(
+	log logr.Logger,
+	ctx context.Context,
+	crd *cachev1alpha1.Samtest,
+	kind string,
+	foundObj client.Object,
+) error {
+	name := foundObj.GetName()
+	if !foundObj.GetDeletionTimestamp().IsZero() {
+		return nil
+	}
+
+	log.Info("resource cannot be updated in place, replacing", "kind", kind, "name", name)
+	err := r.Delete(ctx, foundObj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if client.IgnoreNotFound(err) != nil {
+		log.Error(err, "failed to delete resource for replacement", "kind", kind)
+		k8s.NewDeleteErrorEvent(crd, r.Recorder, kind, name)
+		return err
+	}
+	k8s.NewDeletedEvent(crd, r.Recorder, kind, name)
+	return nil
+}
+
+// Observes every managed resource, reporting its state on the Samtest status
+// and returning a message for each resource which is not yet healthy.
+func (r *SamtestReconciler) observeResources(
+	ctx context.Context,
+	crd *cachev1alpha1.Samtest,
+	managedResources []resources.Resource,
+) ([]string, error) {
+	// Start from a status without any observed state, so nothing is reported
+	// for resources which are gone or no longer managed
+	observed := cachev1alpha1.SamtestStatus{
+		Conditions:         crd.Status.Conditions,
+		ObservedGeneration: crd.Status.ObservedGeneration,
+		LastReconcileTime:  crd.Status.LastReconcileTime,
+		Inventory:          crd.Status.Inventory,
+	}
+	defer func() { crd.Status = observed }()
+
+	unhealthy := []string{}
+	for _, resource := range managedResources {
+		desiredObj := resource.Generate()
+		kind := resource.Kind()
+		name := desiredObj.GetName()
+
+		foundObj := desiredObj.DeepCopyObject().(client.Object)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(desiredObj), foundObj); err != nil {
+			if errors.IsNotFound(err) {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s %s has not been created", kind, name))
+				continue
+			}
+			return nil, err
+		}
+
+		if !foundObj.GetDeletionTimestamp().IsZero() {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s %s is being deleted", kind, name))
+			continue
+		}
+
+		resource.ReportStatus(foundObj, &observed)
+
+		healthy, message, err := resource.IsHealthy(ctx, r.Client, foundObj)
+		if err != nil {
+			return nil, err
+		}
+		if !healthy {
+			unhealthy = append(unhealthy, message)
+		}
+	}
+	return unhealthy, nil
 }
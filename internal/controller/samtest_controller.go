@@ -18,28 +18,58 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/config"
 	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
 	"github.com/s-humphreys/go-operator-sdk/internal/k8s/resources"
 )
 
+// healthRequeueInterval is how often the health of managed resources is
+// checked whilst waiting for them to become ready.
+const healthRequeueInterval = 10 * time.Second
+
 // SamtestReconciler reconciles a Samtest object
 type SamtestReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	Config   config.Config
+
+	// APIReader reads objects straight from the API server, for those the
+	// controller does not cache.
+	APIReader client.Reader
+
+	// gatewayAPIAvailable records whether the Gateway API CRDs were
+	// installed when the controller started.
+	gatewayAPIAvailable bool
 }
 
 // +kubebuilder:rbac:groups=cache.k8s.capitalontap.com,resources=samtests,verbs=get;list;watch;create;update;patch;delete
@@ -47,17 +77,26 @@ type SamtestReconciler struct {
 // +kubebuilder:rbac:groups=cache.k8s.capitalontap.com,resources=samtests/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get
+// +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Samtest object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.21.0/pkg/reconcile
+// Reconcile moves the resources managed for a Samtest towards its spec. A
+// Samtest being deleted is finalized, and a suspended one is left alone.
+// Otherwise the finalizer is added, the RBAC rules and pods are checked
+// against the RBAC ceiling and Pod Security Admission, every resource is
+// applied, those no longer rendered are pruned, and the resources are observed
+// to report the Samtest as Progressing or Ready.
 func (r *SamtestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
@@ -66,72 +105,322 @@ func (r *SamtestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !samtest.DeletionTimestamp.IsZero() {
+		log.Info("resource is being deleted, tearing down managed resources")
+		return r.finalize(ctx, samtest)
+	}
+
 	if samtest.Spec.Suspend {
 		log.Info("resource is suspended, skipping reconciliation")
 		return ctrl.Result{}, nil
 	}
 
-	managedResources := []resources.Resource{
-		&resources.Deployment{},
-		&resources.Service{},
+	if controllerutil.AddFinalizer(samtest, samtestFinalizer) {
+		if err := r.Update(ctx, samtest); err != nil {
+			log.Error(err, "failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if samtest.Spec.Route != nil && !r.gatewayAPIAvailable {
+		err := fmt.Errorf("spec.route is set but the Gateway API CRDs were not installed when the operator started")
+		log.Error(err, "unable to publish route")
+		k8s.NewReconcileErrorEvent(samtest, r.Recorder, err)
+		return ctrl.Result{}, r.updateStatus(ctx, samtest, k8s.ResourcesFailed, err.Error())
 	}
 
-	// TODO(Sam) - determine if there is a diff (do in prior loop, early return & update status)
+	checksum, err := r.configChecksum(ctx, samtest)
+	if err != nil {
+		log.Error(err, "failed to hash referenced configuration")
+		_ = r.updateStatus(ctx, samtest, k8s.ResourcesFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	managedResources := r.managedResources(samtest, checksum)
 
-	if err := r.updateStatus(ctx, samtest, k8s.NewStatusCondition(k8s.ProgressingResources)); err != nil {
+	// Nothing is applied whilst the RBAC rules grant more than the operator
+	// allows, so the workload never runs with a partial set of permissions.
+	// The condition is only reported whilst there are rules to check.
+	if samtest.Spec.RBAC != nil {
+		exceeding := k8s.ExceedingPermissions(r.Config.RBACCeiling, samtest.Spec.RBAC.Rules)
+		if len(exceeding) > 0 {
+			err := fmt.Errorf("rbac rules grant more than the operator allows: %s", strings.Join(exceeding, "; "))
+			log.Info("refusing rbac rules exceeding the ceiling", "exceeding", exceeding)
+			k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Set(k8s.RulesExceedCeiling, err.Error())
+			k8s.NewReconcileErrorEvent(samtest, r.Recorder, err)
+			return ctrl.Result{}, r.updateStatus(ctx, samtest, k8s.ResourcesFailed, err.Error())
+		}
+		k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Set(k8s.RulesWithinCeiling, "")
+	} else {
+		k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Remove(k8s.ConditionRBACRefused)
+	}
+
+	// Nothing is applied whilst the pods would be rejected by Pod Security
+	// Admission. The namespace is not watched, so a violation is requeued to
+	// pick up a change to its enforced level. The condition is only reported
+	// whilst the namespace has an enforce label.
+	level, violations, err := r.checkPodSecurity(ctx, samtest.Namespace, managedResources)
+	if err != nil {
+		log.Error(err, "failed to check pod security")
+		_ = r.updateStatus(ctx, samtest, k8s.ResourcesFailed, err.Error())
 		return ctrl.Result{}, err
 	}
+	if len(violations) > 0 {
+		err := fmt.Errorf("pods violate the %s Pod Security Standard: %s", level, strings.Join(violations, "; "))
+		log.Info("pods violate the pod security standard of the namespace", "level", level.String())
+		k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Set(k8s.PodSecurityViolation, err.Error())
+		k8s.NewReconcileErrorEvent(samtest, r.Recorder, err)
+		return ctrl.Result{RequeueAfter: healthRequeueInterval}, r.updateStatus(ctx, samtest, k8s.ResourcesFailed, err.Error())
+	}
+	if level.Level != "" {
+		k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Set(k8s.PodSecurityCompliant, "")
+	} else {
+		k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Remove(k8s.ConditionPodSecurityViolated)
+	}
+
+	// Lifecycle conditions which have not been reported yet start out Unknown,
+	// whilst those already reported are kept until this pass sets one of them
+	k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Initialize()
 
 	// Reconcile each resource
 	var wg sync.WaitGroup
 	errs := make(chan error, len(managedResources))
 
 	for _, resource := range managedResources {
-		res := resource.New(samtest)
 		wg.Add(1)
 
 		go func(reconcileResource resources.Resource) {
 			defer wg.Done()
 			if _, err := r.reconcileResource(log, ctx, samtest, reconcileResource); err != nil {
-				errs <- err
+				// Tag the error with the resource it came from
+				name := reconcileResource.Generate().GetName()
+				errs <- fmt.Errorf("%s %s: %w", reconcileResource.Kind(), name, err)
 			}
-		}(res)
+		}(resource)
 	}
 
 	wg.Wait()
 	close(errs)
 
+	// Collect every error, sorted so the reported message is stable between passes
+	reconcileErrs := []error{}
 	for err := range errs {
-		if err != nil {
-			_ = r.updateStatus(ctx, samtest, k8s.NewStatusCondition(k8s.ResourcesFailed))
+		reconcileErrs = append(reconcileErrs, err)
+	}
+	slices.SortFunc(reconcileErrs, func(a, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+
+	if len(reconcileErrs) > 0 {
+		conflicts := []error{}
+		for _, err := range reconcileErrs {
+			if errors.IsConflict(err) {
+				conflicts = append(conflicts, err)
+			}
+		}
+		if len(conflicts) > 0 {
+			k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).
+				Set(k8s.FieldConflict, utilerrors.NewAggregate(conflicts).Error())
+		}
+
+		aggregate := utilerrors.NewAggregate(reconcileErrs)
+		k8s.NewReconcileErrorEvent(samtest, r.Recorder, aggregate)
+		_ = r.updateStatus(ctx, samtest, k8s.ResourcesFailed, aggregate.Error())
+		return ctrl.Result{}, aggregate
+	}
+
+	// Every resource applied cleanly, so any earlier conflict has been resolved
+	k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Set(k8s.NoFieldConflicts, "")
+
+	// Prune resources which are no longer rendered for the Samtest
+	inventory := newInventory(managedResources)
+	if err := r.prune(log, ctx, samtest, inventory); err != nil {
+		_ = r.updateStatus(ctx, samtest, k8s.ResourcesFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+	samtest.Status.Inventory = inventory
+
+	// Only report ready once every resource is healthy, requeueing until then
+	unhealthy, err := r.observeResources(ctx, samtest, managedResources)
+	if err != nil {
+		_ = r.updateStatus(ctx, samtest, k8s.ResourcesFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if len(unhealthy) > 0 {
+		log.Info("waiting for resources to become healthy", "unhealthy", unhealthy)
+		if err := r.updateStatus(ctx, samtest, k8s.ProgressingResources, strings.Join(unhealthy, "; ")); err != nil {
 			return ctrl.Result{}, err
 		}
+		return ctrl.Result{RequeueAfter: healthRequeueInterval}, nil
 	}
 
-	if err := r.updateStatus(ctx, samtest, k8s.NewStatusCondition(k8s.ResourcesReady)); err != nil {
+	if err := r.updateStatus(ctx, samtest, k8s.ResourcesReady, ""); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// Returns the resources managed for the Samtest, in dependency order. Resources
+// are created in this order and torn down in reverse. The checksum of the
+// referenced configuration is stamped on the pod template.
+func (r *SamtestReconciler) managedResources(samtest *cachev1alpha1.Samtest, checksum string) []resources.Resource {
+	prototypes := []resources.Resource{}
+	if resources.GeneratesServiceAccount(samtest) {
+		prototypes = append(prototypes, &resources.ServiceAccount{})
+	}
+	if samtest.Spec.RBAC != nil {
+		prototypes = append(prototypes, &resources.Role{}, &resources.RoleBinding{})
+	}
+	if samtest.Spec.Persistence != nil {
+		prototypes = append(prototypes, &resources.PersistentVolumeClaim{})
+	}
+	prototypes = append(prototypes,
+		&resources.Deployment{ResourceProfiles: r.Config.ResourceProfiles, ConfigChecksum: checksum},
+	)
+	if samtest.Spec.Autoscaling != nil {
+		prototypes = append(prototypes, &resources.HorizontalPodAutoscaler{})
+	}
+	if samtest.Spec.DisruptionBudget != nil && minimumReplicas(samtest) > 1 {
+		prototypes = append(prototypes, &resources.PodDisruptionBudget{})
+	}
+	if samtest.Spec.Service == nil || !samtest.Spec.Service.Disabled {
+		prototypes = append(prototypes, &resources.Service{ClusterDomain: r.Config.ClusterDomain})
+	}
+	if samtest.Spec.Ingress != nil {
+		prototypes = append(prototypes, &resources.Ingress{})
+	}
+	if samtest.Spec.Route != nil && r.gatewayAPIAvailable {
+		prototypes = append(prototypes, &resources.HTTPRoute{})
+	}
+
+	managedResources := make([]resources.Resource, 0, len(prototypes))
+	for _, prototype := range prototypes {
+		managedResources = append(managedResources, prototype.New(samtest))
+	}
+	return managedResources
+}
+
+// Returns the fewest replicas the Samtest can be scaled to, by its replicas or
+// by its autoscaler.
+func minimumReplicas(samtest *cachev1alpha1.Samtest) int32 {
+	if samtest.Spec.Autoscaling == nil {
+		return samtest.Spec.Replicas
+	}
+	return ptr.Deref(samtest.Spec.Autoscaling.MinReplicas, 1)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *SamtestReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	ctx := context.Background()
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &cachev1alpha1.Samtest{}, configMapIndexKey,
+		func(obj client.Object) []string {
+			return referencedConfigMaps(obj.(*cachev1alpha1.Samtest))
+		}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &cachev1alpha1.Samtest{}, secretIndexKey,
+		func(obj client.Object) []string {
+			return referencedSecrets(obj.(*cachev1alpha1.Samtest))
+		}); err != nil {
+		return err
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&cachev1alpha1.Samtest{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
 		Owns(&corev1.Service{}).
-		Named("samtest").
-		Complete(r)
+		Owns(&networkingv1.Ingress{}).
+		// Only the metadata of ConfigMaps and Secrets is cached, as the watch
+		// spans the cluster and their data is read by configChecksum directly
+		WatchesMetadata(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.samtestsForConfigMap)).
+		WatchesMetadata(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.samtestsForSecret))
+
+	// HTTPRoutes are only watched when the Gateway API CRDs are installed,
+	// as the watch would otherwise fail to start
+	available, err := gatewayAPIInstalled(mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+	r.gatewayAPIAvailable = available
+	if available {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(resources.HTTPRouteGroupVersionKind)
+		builder = builder.Owns(route)
+	}
+
+	return builder.Named("samtest").Complete(r)
 }
 
-// Updates the status of the Samtest resource with a provided condition.
-func (r *SamtestReconciler) updateStatus(ctx context.Context, samtest *cachev1alpha1.Samtest, condition metav1.Condition) error {
+// Reports whether the Gateway API HTTPRoute CRD is installed in the cluster.
+func gatewayAPIInstalled(mapper meta.RESTMapper) (bool, error) {
+	gvk := resources.HTTPRouteGroupVersionKind
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Updates the status of the Samtest resource with the condition for a provided
+// reason. A non-empty message replaces the default message of the reason. The
+// status is merge patched, retrying on conflicts, and the write is skipped when
+// nothing other than the reconcile time would change. The status compared
+// against is read from the API server, as the cache may not yet hold an
+// earlier write and skipping on it would leave the status stale.
+func (r *SamtestReconciler) updateStatus(
+	ctx context.Context,
+	samtest *cachev1alpha1.Samtest,
+	reason k8s.ConditionReason,
+	message string,
+) error {
 	log := logf.FromContext(ctx)
-	meta.SetStatusCondition(&samtest.Status.Conditions, condition)
-	if err := r.Status().Update(ctx, samtest); err != nil {
+	k8s.NewConditionManager(&samtest.Status.Conditions, samtest.Generation).Set(reason, message)
+	samtest.Status.ObservedGeneration = samtest.Generation
+	desired := samtest.Status.DeepCopy()
+
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &cachev1alpha1.Samtest{}
+		if err := r.APIReader.Get(ctx, client.ObjectKeyFromObject(samtest), latest); err != nil {
+			return err
+		}
+
+		if !statusChanged(latest.Status, *desired) {
+			return nil
+		}
+
+		patch := client.MergeFromWithOptions(latest.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		latest.Status = *desired.DeepCopy()
+		latest.Status.LastReconcileTime = ptr.To(metav1.Now())
+		if err := r.Status().Patch(ctx, latest, patch); err != nil {
+			return err
+		}
+
+		samtest.Status = latest.Status
+		samtest.ResourceVersion = latest.ResourceVersion
+		return nil
+	})
+	if err != nil {
+		condition := k8s.NewStatusCondition(reason)
 		log.Error(err, "failed to update status", "conditionType", condition.Type, "conditionStatus", condition.Status)
 		return err
 	}
 	return nil
 }
+
+// Reports whether the desired status differs from the current status, ignoring
+// the reconcile time which would otherwise differ on every pass.
+func statusChanged(current cachev1alpha1.SamtestStatus, desired cachev1alpha1.SamtestStatus) bool {
+	current.LastReconcileTime = nil
+	desired.LastReconcileTime = nil
+	return !equality.Semantic.DeepEqual(current, desired)
+}
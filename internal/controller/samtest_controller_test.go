@@ -18,69 +18,1401 @@ package controller
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/config"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s/resources"
 )
 
 var _ = Describe("Samtest Controller", func() {
-	Context("When reconciling a resource", func() {
-		const resourceName = "test-resource"
+	const resourceName = "test-resource"
 
-		ctx := context.Background()
+	ctx := context.Background()
+
+	typeNamespacedName := types.NamespacedName{
+		Name:      resourceName,
+		Namespace: "default",
+	}
+	request := reconcile.Request{NamespacedName: typeNamespacedName}
+
+	var controllerReconciler *SamtestReconciler
+
+	// Reconciles the Samtest once and returns it as stored afterwards.
+	reconcileSamtest := func() *cachev1alpha1.Samtest {
+		GinkgoHelper()
+		_, err := controllerReconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
 
-		typeNamespacedName := types.NamespacedName{
-			Name:      resourceName,
-			Namespace: "default", // TODO(user):Modify as needed
-		}
 		samtest := &cachev1alpha1.Samtest{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, samtest)).To(Succeed())
+		return samtest
+	}
 
-		BeforeEach(func() {
-			By("creating the custom resource for the Kind Samtest")
-			err := k8sClient.Get(ctx, typeNamespacedName, samtest)
-			if err != nil && errors.IsNotFound(err) {
-				resource := &cachev1alpha1.Samtest{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      resourceName,
-						Namespace: "default",
-					},
-					Spec: cachev1alpha1.SamtestSpec{
-						Image: "nginx:latest",
+	// Applies a change to the spec of the stored Samtest.
+	updateSamtest := func(mutate func(samtest *cachev1alpha1.Samtest)) {
+		GinkgoHelper()
+		samtest := &cachev1alpha1.Samtest{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, samtest)).To(Succeed())
+		mutate(samtest)
+		Expect(k8sClient.Update(ctx, samtest)).To(Succeed())
+	}
+
+	// Reads a managed resource, which shares the name of the Samtest.
+	getManaged := func(obj client.Object) {
+		GinkgoHelper()
+		Expect(k8sClient.Get(ctx, typeNamespacedName, obj)).To(Succeed())
+	}
+
+	// Expects a managed resource to be absent.
+	expectNoManaged := func(obj client.Object) {
+		GinkgoHelper()
+		err := k8sClient.Get(ctx, typeNamespacedName, obj)
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	}
+
+	// Deletes the Samtest and reconciles until it is gone, letting the
+	// finalizer tear down the managed resources.
+	deleteSamtest := func() {
+		GinkgoHelper()
+		samtest := &cachev1alpha1.Samtest{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, samtest)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, samtest)).To(Succeed())
+		Eventually(func() error {
+			if _, err := controllerReconciler.Reconcile(ctx, request); err != nil {
+				return err
+			}
+			return k8sClient.Get(ctx, typeNamespacedName, &cachev1alpha1.Samtest{})
+		}).Should(Satisfy(errors.IsNotFound))
+	}
+
+	BeforeEach(func() {
+		controllerReconciler = &SamtestReconciler{
+			Client:    k8sClient,
+			APIReader: k8sClient,
+			Scheme:    k8sClient.Scheme(),
+			Recorder:  record.NewFakeRecorder(100),
+			Config:    config.Default(),
+		}
+
+		By("creating the custom resource for the Kind Samtest")
+		err := k8sClient.Get(ctx, typeNamespacedName, &cachev1alpha1.Samtest{})
+		if err != nil && errors.IsNotFound(err) {
+			resource := &cachev1alpha1.Samtest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: cachev1alpha1.SamtestSpec{
+					Image:    "nginx:latest",
+					Replicas: 1,
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		err := k8sClient.Get(ctx, typeNamespacedName, &cachev1alpha1.Samtest{})
+		if err == nil {
+			By("Cleanup the specific resource instance Samtest")
+			deleteSamtest()
+		} else {
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		}
+
+		// envtest runs no garbage collector, so released resources are removed by hand
+		By("Cleanup the resources managed by the Samtest")
+		managed := []client.Object{
+			&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+			&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}},
+		}
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(resources.HTTPRouteGroupVersionKind)
+		route.SetName(resourceName)
+		route.SetNamespace("default")
+		managed = append(managed, route)
+		for _, obj := range managed {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, obj))).To(Succeed())
+		}
+	})
+
+	Describe("applying the Deployment", func() {
+		It("should create the Deployment with the replicas and image of the Samtest", func() {
+			reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			Expect(deployment.Spec.Replicas).To(HaveValue(Equal(int32(1))))
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:latest"))
+		})
+
+		It("should update the Deployment when the image changes", func() {
+			reconcileSamtest()
+
+			By("Changing the image on the custom resource")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Image = "nginx:1.27"
+			})
+			reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.27"))
+		})
+
+		It("should remove fields dropped from the Samtest from the Deployment", func() {
+			By("Setting env, tolerations and a priority class")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Env = []corev1.EnvVar{
+					{Name: "LOG_LEVEL", Value: "debug"},
+					{Name: "LOG_FORMAT", Value: "json"},
+				}
+				samtest.Spec.Tolerations = []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+					{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+				}
+				samtest.Spec.PriorityClassName = "system-cluster-critical"
+			})
+			reconcileSamtest()
+
+			By("Dropping one env var, one toleration and the priority class")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Env = samtest.Spec.Env[:1]
+				samtest.Spec.Tolerations = samtest.Spec.Tolerations[:1]
+				samtest.Spec.PriorityClassName = ""
+			})
+			reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"}))
+			Expect(podSpec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "LOG_FORMAT")))
+			Expect(podSpec.Tolerations).To(HaveLen(1))
+			Expect(podSpec.Tolerations[0].Key).To(Equal("dedicated"))
+			Expect(podSpec.PriorityClassName).To(BeEmpty())
+		})
+	})
+
+	Describe("compute resources", func() {
+		It("should merge explicit compute resources over the resource profile", func() {
+			By("Selecting a resource profile and overriding its memory limit")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.ResourceProfile = cachev1alpha1.ResourceProfileSmall
+				samtest.Spec.Resources = &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: k8sresource.MustParse("1Gi")},
+				}
+			})
+			resource := reconcileSamtest()
+			Expect(resource.Status.QOSClass).To(Equal(corev1.PodQOSBurstable))
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Resources.Requests.Cpu().String()).To(Equal("100m"))
+			Expect(container.Resources.Requests.Memory().String()).To(Equal("128Mi"))
+			Expect(container.Resources.Limits.Memory().String()).To(Equal("1Gi"))
+
+			By("Requesting more memory than the profile limits")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Resources = &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: k8sresource.MustParse("512Mi")},
+				}
+			})
+			reconcileSamtest()
+
+			// The profile limit is raised to the request rather than left below it
+			getManaged(deployment)
+			container = deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.Resources.Requests.Memory().String()).To(Equal("512Mi"))
+			Expect(container.Resources.Limits.Memory().String()).To(Equal("512Mi"))
+		})
+	})
+
+	Describe("environment and configuration", func() {
+		It("should render the environment of the main container", func() {
+			By("Setting literal, secret and downward API environment variables")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Env = []corev1.EnvVar{
+					{Name: "LOG_LEVEL", Value: "debug"},
+					{Name: "API_TOKEN", ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "api"},
+							Key:                  "token",
+						},
+					}},
+					{Name: "NODE_NAME", ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+					}},
+				}
+				samtest.Spec.EnvFrom = []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}},
+				}
+			})
+			reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			container := deployment.Spec.Template.Spec.Containers[0]
+			names := []string{}
+			for _, envVar := range container.Env {
+				names = append(names, envVar.Name)
+			}
+			Expect(names).To(Equal([]string{
+				"SAMTEST_NAME", "SAMTEST_POD_NAME", "SAMTEST_POD_NAMESPACE", "LOG_LEVEL", "API_TOKEN", "NODE_NAME",
+			}))
+			Expect(container.EnvFrom).To(HaveLen(1))
+			Expect(container.EnvFrom[0].ConfigMapRef.Name).To(Equal("settings"))
+		})
+
+		It("should reject duplicate and reserved environment variable names", func() {
+			resource := &cachev1alpha1.Samtest{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			By("Setting the same name twice")
+			duplicate := resource.DeepCopy()
+			duplicate.Spec.Env = []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "LOG_LEVEL", Value: "info"}}
+			Expect(errors.IsInvalid(k8sClient.Update(ctx, duplicate))).To(BeTrue())
+
+			By("Setting a name with the reserved prefix")
+			reserved := resource.DeepCopy()
+			reserved.Spec.Env = []corev1.EnvVar{{Name: "SAMTEST_NAME", Value: "other"}}
+			Expect(errors.IsInvalid(k8sClient.Update(ctx, reserved))).To(BeTrue())
+
+			By("Setting an envFrom prefix with the reserved prefix")
+			reservedPrefix := resource.DeepCopy()
+			reservedPrefix.Spec.EnvFrom = []corev1.EnvFromSource{{
+				Prefix:       "SAMTEST_",
+				ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}},
+			}}
+			Expect(errors.IsInvalid(k8sClient.Update(ctx, reservedPrefix))).To(BeTrue())
+		})
+
+		It("should roll the pods when referenced configuration changes", func() {
+			By("Creating a ConfigMap and referencing it from the Samtest")
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+				Data:       map[string]string{"LOG_LEVEL": "debug"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, configMap))).To(Succeed())
+			})
+
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.EnvFrom = []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}},
+				}
+			})
+
+			checksum := func() string {
+				reconcileSamtest()
+				deployment := &appsv1.Deployment{}
+				getManaged(deployment)
+				return deployment.Spec.Template.Annotations[resources.ConfigChecksumAnnotation]
+			}
+
+			By("Reconciling the resource")
+			initial := checksum()
+			Expect(initial).NotTo(BeEmpty())
+
+			By("Reconciling again without changes")
+			Expect(checksum()).To(Equal(initial))
+
+			By("Changing the data of the ConfigMap")
+			configMap.Data["LOG_LEVEL"] = "info"
+			Expect(k8sClient.Update(ctx, configMap)).To(Succeed())
+			Expect(checksum()).NotTo(Equal(initial))
+
+			By("Dropping the reference to the ConfigMap")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.EnvFrom = nil
+			})
+			Expect(checksum()).To(BeEmpty())
+		})
+	})
+
+	Describe("ports and probes", func() {
+		It("should drive the container and Service ports from the port list", func() {
+			By("Listing an http and a metrics port")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Ports = []cachev1alpha1.Port{
+					{Name: "http", ContainerPort: 8080, ServicePort: 80, AppProtocol: ptr.To("http")},
+					{Name: "metrics", ContainerPort: 9090},
+				}
+			})
+			reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			ports := deployment.Spec.Template.Spec.Containers[0].Ports
+			Expect(ports).To(HaveLen(2))
+			Expect(ports[0].Name).To(Equal("http"))
+			Expect(ports[0].ContainerPort).To(Equal(int32(8080)))
+			Expect(ports[1].Name).To(Equal("metrics"))
+			Expect(ports[1].ContainerPort).To(Equal(int32(9090)))
+
+			service := &corev1.Service{}
+			getManaged(service)
+			Expect(service.Spec.Ports).To(HaveLen(2))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(80)))
+			Expect(service.Spec.Ports[0].TargetPort.StrVal).To(Equal("http"))
+			Expect(service.Spec.Ports[0].AppProtocol).To(HaveValue(Equal("http")))
+			Expect(service.Spec.Ports[1].Port).To(Equal(int32(9090)))
+			Expect(service.Spec.Ports[1].TargetPort.StrVal).To(Equal("metrics"))
+		})
+
+		It("should reject duplicate port names and numbers", func() {
+			resource := &cachev1alpha1.Samtest{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			By("Listing the same name twice")
+			duplicateName := resource.DeepCopy()
+			duplicateName.Spec.Ports = []cachev1alpha1.Port{
+				{Name: "http", ContainerPort: 8080},
+				{Name: "http", ContainerPort: 8081},
+			}
+			Expect(errors.IsInvalid(k8sClient.Update(ctx, duplicateName))).To(BeTrue())
+
+			By("Listing the same container port twice")
+			duplicatePort := resource.DeepCopy()
+			duplicatePort.Spec.Ports = []cachev1alpha1.Port{
+				{Name: "http", ContainerPort: 8080},
+				{Name: "admin", ContainerPort: 8080},
+			}
+			Expect(errors.IsInvalid(k8sClient.Update(ctx, duplicatePort))).To(BeTrue())
+
+			By("Publishing two ports on the same Service port")
+			duplicateServicePort := resource.DeepCopy()
+			duplicateServicePort.Spec.Ports = []cachev1alpha1.Port{
+				{Name: "http", ContainerPort: 8080, ServicePort: 80},
+				{Name: "admin", ContainerPort: 80},
+			}
+			Expect(errors.IsInvalid(k8sClient.Update(ctx, duplicateServicePort))).To(BeTrue())
+		})
+
+		It("should probe the main container", func() {
+			reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			container := deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.ReadinessProbe).NotTo(BeNil())
+			Expect(container.ReadinessProbe.HTTPGet.Port.StrVal).To(Equal("http"))
+			Expect(container.LivenessProbe).To(BeNil())
+
+			By("Setting a liveness probe referencing a named port")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Ports = []cachev1alpha1.Port{{Name: "web", ContainerPort: 8080}}
+				samtest.Spec.LivenessProbe = &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("web")},
 					},
 				}
-				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			})
+			resource := reconcileSamtest()
+
+			getManaged(deployment)
+			container = deployment.Spec.Template.Spec.Containers[0]
+			Expect(container.LivenessProbe.HTTPGet.Port.StrVal).To(Equal("web"))
+			// Without a port named http there is no default readiness probe
+			Expect(container.ReadinessProbe).To(BeNil())
+
+			By("Referencing a port which is not listed")
+			resource.Spec.LivenessProbe.HTTPGet.Port = intstr.FromString("admin")
+			Expect(errors.IsInvalid(k8sClient.Update(ctx, resource))).To(BeTrue())
+		})
+	})
+
+	Describe("the Service", func() {
+		It("should keep the allocated node ports and cluster IP across Service updates", func() {
+			By("Publishing a LoadBalancer Service")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Service = &cachev1alpha1.ServiceSpec{Type: cachev1alpha1.ServiceTypeLoadBalancer}
+			})
+			reconcileSamtest()
+
+			service := &corev1.Service{}
+			getManaged(service)
+			Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+			clusterIP := service.Spec.ClusterIP
+			nodePort := service.Spec.Ports[0].NodePort
+			Expect(nodePort).NotTo(BeZero())
+
+			By("Updating the Service")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Service.Annotations = map[string]string{"example.com/scheme": "internal"}
+				samtest.Spec.Service.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+			})
+			reconcileSamtest()
+
+			getManaged(service)
+			Expect(service.Annotations).To(HaveKeyWithValue("example.com/scheme", "internal"))
+			Expect(service.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyLocal))
+			Expect(service.Spec.ClusterIP).To(Equal(clusterIP))
+			Expect(service.Spec.Ports[0].NodePort).To(Equal(nodePort))
+
+			By("Assigning the load balancer an address")
+			service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.10"}}
+			Expect(k8sClient.Status().Update(ctx, service)).To(Succeed())
+
+			resource := reconcileSamtest()
+			Expect(resource.Status.LoadBalancerAddresses).To(Equal([]string{"203.0.113.10"}))
+		})
+
+		It("should delete the Service once disabled", func() {
+			reconcileSamtest()
+			getManaged(&corev1.Service{})
+
+			By("Disabling the Service")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Service = &cachev1alpha1.ServiceSpec{Disabled: true}
+			})
+			resource := reconcileSamtest()
+
+			expectNoManaged(&corev1.Service{})
+			Expect(resource.Status.ClusterIP).To(BeEmpty())
+		})
+	})
+
+	Describe("the Ingress", func() {
+		It("should publish the Service through an Ingress when configured", func() {
+			By("Configuring an Ingress")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Ingress = &cachev1alpha1.IngressSpec{
+					IngressClassName: ptr.To("nginx"),
+					Hosts:            []string{"samtest.example.com"},
+					Paths:            []cachev1alpha1.IngressPath{{Path: "/api"}},
+					TLSSecretName:    "samtest-tls",
+				}
+			})
+			reconcileSamtest()
+
+			ingress := &networkingv1.Ingress{}
+			getManaged(ingress)
+			Expect(ingress.Spec.IngressClassName).To(HaveValue(Equal("nginx")))
+			Expect(ingress.Spec.Rules).To(HaveLen(1))
+			Expect(ingress.Spec.Rules[0].Host).To(Equal("samtest.example.com"))
+			path := ingress.Spec.Rules[0].HTTP.Paths[0]
+			Expect(path.Path).To(Equal("/api"))
+			Expect(path.PathType).To(HaveValue(Equal(networkingv1.PathTypePrefix)))
+			Expect(path.Backend.Service.Name).To(Equal(resourceName))
+			Expect(path.Backend.Service.Port.Name).To(Equal("http"))
+			Expect(ingress.Spec.TLS).To(HaveLen(1))
+			Expect(ingress.Spec.TLS[0].SecretName).To(Equal("samtest-tls"))
+
+			By("Assigning the Ingress an address")
+			ingress.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{Hostname: "lb.example.com"}}
+			Expect(k8sClient.Status().Update(ctx, ingress)).To(Succeed())
+
+			resource := reconcileSamtest()
+			Expect(resource.Status.IngressAddresses).To(Equal([]string{"lb.example.com"}))
+
+			By("Removing the Ingress")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Ingress = nil
+			})
+			reconcileSamtest()
+
+			expectNoManaged(&networkingv1.Ingress{})
+		})
+	})
+
+	Describe("the HTTPRoute", func() {
+		// Reports the route as accepted by each of its parent Gateways, as a
+		// Gateway controller would.
+		acceptRoute := func() error {
+			route := &unstructured.Unstructured{}
+			route.SetGroupVersionKind(resources.HTTPRouteGroupVersionKind)
+			if err := k8sClient.Get(ctx, typeNamespacedName, route); err != nil {
+				return err
+			}
+			parentRefs, _, err := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+			if err != nil {
+				return err
+			}
+			parents := make([]interface{}, 0, len(parentRefs))
+			for _, parentRef := range parentRefs {
+				parents = append(parents, map[string]interface{}{
+					"parentRef":      parentRef,
+					"controllerName": "example.com/gateway-controller",
+					"conditions": []interface{}{map[string]interface{}{
+						"type":               "Accepted",
+						"status":             "True",
+						"reason":             "Accepted",
+						"message":            "Route is accepted",
+						"observedGeneration": route.GetGeneration(),
+						"lastTransitionTime": metav1.Now().UTC().Format(time.RFC3339),
+					}},
+				})
+			}
+			if err := unstructured.SetNestedSlice(route.Object, parents, "status", "parents"); err != nil {
+				return err
 			}
+			return k8sClient.Status().Update(ctx, route)
+		}
+
+		It("should render an HTTPRoute splitting requests across backends", func() {
+			resource := &cachev1alpha1.Samtest{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Ports = []cachev1alpha1.Port{{Name: "http", ContainerPort: 8080, ServicePort: 80}}
+			resource.Spec.Route = &cachev1alpha1.RouteSpec{
+				ParentRefs: []cachev1alpha1.RouteParentRef{{Name: "public", Namespace: "gateways"}},
+				Hostnames:  []string{"samtest.example.com"},
+				Matches:    []cachev1alpha1.RoutePathMatch{{Type: "PathPrefix", Value: "/api"}},
+				BackendRefs: []cachev1alpha1.RouteBackendRef{
+					{Weight: 90},
+					{Name: "samtest-canary", Port: 8080, Weight: 10},
+				},
+				Port: "http",
+			}
+
+			route := (&resources.HTTPRoute{}).New(resource).Generate().(*unstructured.Unstructured)
+			Expect(route.GroupVersionKind()).To(Equal(resources.HTTPRouteGroupVersionKind))
+
+			parentRefs, _, err := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parentRefs).To(ConsistOf(HaveKeyWithValue("namespace", "gateways")))
+
+			rules, _, err := unstructured.NestedSlice(route.Object, "spec", "rules")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(HaveLen(1))
+			backendRefs := rules[0].(map[string]interface{})["backendRefs"].([]interface{})
+			Expect(backendRefs).To(HaveLen(2))
+			Expect(backendRefs[0]).To(And(
+				HaveKeyWithValue("name", resourceName),
+				HaveKeyWithValue("port", int64(80)),
+				HaveKeyWithValue("weight", int64(90)),
+			))
+			Expect(backendRefs[1]).To(And(
+				HaveKeyWithValue("name", "samtest-canary"),
+				HaveKeyWithValue("port", int64(8080)),
+				HaveKeyWithValue("weight", int64(10)),
+			))
 		})
 
-		AfterEach(func() {
-			// TODO(user): Cleanup logic after each test, like removing the resource instance.
+		It("should only count a Gateway in the namespace of the route for a parent without one", func() {
 			resource := &cachev1alpha1.Samtest{}
-			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Route = &cachev1alpha1.RouteSpec{
+				ParentRefs: []cachev1alpha1.RouteParentRef{{Name: "public"}},
+			}
+			route := (&resources.HTTPRoute{}).New(resource)
+
+			// Builds the route as reported by a Gateway controller which
+			// accepted it on a parent in the provided namespace
+			acceptedIn := func(namespace string) client.Object {
+				parentRef := map[string]interface{}{"name": "public"}
+				if namespace != "" {
+					parentRef["namespace"] = namespace
+				}
+				found := route.Generate().(*unstructured.Unstructured)
+				Expect(unstructured.SetNestedSlice(found.Object, []interface{}{map[string]interface{}{
+					"parentRef": parentRef,
+					"conditions": []interface{}{map[string]interface{}{
+						"type":               "Accepted",
+						"status":             "True",
+						"reason":             "Accepted",
+						"lastTransitionTime": metav1.Now().UTC().Format(time.RFC3339),
+					}},
+				}}, "status", "parents")).To(Succeed())
+				return found
+			}
+
+			for namespace, want := range map[string]bool{"": true, "default": true, "gateways": false} {
+				healthy, _, err := route.IsHealthy(ctx, k8sClient, acceptedIn(namespace))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(healthy).To(Equal(want), "accepted in namespace %q", namespace)
+			}
+		})
+
+		It("should fail a route when the Gateway API is not installed", func() {
+			By("Configuring a route")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Route = &cachev1alpha1.RouteSpec{
+					ParentRefs: []cachev1alpha1.RouteParentRef{{Name: "public"}},
+				}
+			})
+
+			By("Reconciling without the Gateway API CRDs")
+			resource := reconcileSamtest()
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "Failed")).To(BeTrue())
+			failed := meta.FindStatusCondition(resource.Status.Conditions, "Failed")
+			Expect(failed.Message).To(ContainSubstring("Gateway API"))
+		})
+
+		It("should apply an HTTPRoute and reflect its acceptance in the status", func() {
+			By("Configuring a route")
+			controllerReconciler.gatewayAPIAvailable = true
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Route = &cachev1alpha1.RouteSpec{
+					ParentRefs: []cachev1alpha1.RouteParentRef{{Name: "public", Namespace: "gateways"}},
+					Hostnames:  []string{"samtest.example.com"},
+				}
+			})
+			resource := reconcileSamtest()
+			Expect(resource.Status.RouteParents).To(BeEmpty())
+
+			route := &unstructured.Unstructured{}
+			route.SetGroupVersionKind(resources.HTTPRouteGroupVersionKind)
+			getManaged(route)
+			Expect(metav1.IsControlledBy(route, resource)).To(BeTrue())
+			hostnames, _, err := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
 			Expect(err).NotTo(HaveOccurred())
+			Expect(hostnames).To(ConsistOf("samtest.example.com"))
 
-			By("Cleanup the specific resource instance Samtest")
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			By("Accepting the route on its Gateway")
+			Expect(acceptRoute()).To(Succeed())
+
+			resource = reconcileSamtest()
+			Expect(resource.Status.RouteParents).To(HaveLen(1))
+			Expect(resource.Status.RouteParents[0].Name).To(Equal("public"))
+			Expect(resource.Status.RouteParents[0].Namespace).To(Equal("gateways"))
+			Expect(meta.IsStatusConditionTrue(resource.Status.RouteParents[0].Conditions, "Accepted")).To(BeTrue())
 		})
-		It("should successfully reconcile the resource", func() {
-			By("Reconciling the created resource")
-			controllerReconciler := &SamtestReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+
+		It("should watch HTTPRoutes once the Gateway API CRDs are detected", func() {
+			By("Setting up the controller with a manager")
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+				Scheme:  k8sClient.Scheme(),
+				Metrics: metricsserver.Options{BindAddress: "0"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			managedReconciler := &SamtestReconciler{
+				Client:    mgr.GetClient(),
+				APIReader: mgr.GetAPIReader(),
+				Scheme:    mgr.GetScheme(),
+				Recorder:  mgr.GetEventRecorderFor("samtest-controller"),
+				Config:    config.Default(),
 			}
+			Expect(managedReconciler.SetupWithManager(mgr)).To(Succeed())
+			Expect(managedReconciler.gatewayAPIAvailable).To(BeTrue())
+
+			mgrCtx, stopManager := context.WithCancel(ctx)
+			stopped := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(stopped)
+				Expect(mgr.Start(mgrCtx)).To(Succeed())
+			}()
+			// The manager is stopped before the Samtest is torn down by hand
+			defer func() {
+				stopManager()
+				<-stopped
+			}()
+
+			By("Configuring a route")
+			// The running controller writes to the Samtest too, so conflicts are retried
+			resource := &cachev1alpha1.Samtest{}
+			Eventually(func() error {
+				if err := k8sClient.Get(ctx, typeNamespacedName, resource); err != nil {
+					return err
+				}
+				resource.Spec.Route = &cachev1alpha1.RouteSpec{
+					ParentRefs: []cachev1alpha1.RouteParentRef{{Name: "public"}},
+				}
+				return k8sClient.Update(ctx, resource)
+			}).Should(Succeed())
+
+			By("Accepting the route on its Gateway once it is applied")
+			Eventually(acceptRoute).Should(Succeed())
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: typeNamespacedName,
+			// The change to the route alone triggers the reconcile, well within
+			// the interval the unhealthy Samtest is requeued at
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+				g.Expect(resource.Status.RouteParents).To(HaveLen(1))
+				g.Expect(meta.IsStatusConditionTrue(resource.Status.RouteParents[0].Conditions, "Accepted")).To(BeTrue())
+			}).WithTimeout(healthRequeueInterval / 2).Should(Succeed())
+		})
+	})
+
+	Describe("autoscaling", func() {
+		It("should leave the replicas to the HorizontalPodAutoscaler whilst autoscaling", func() {
+			By("Enabling autoscaling")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Autoscaling = &cachev1alpha1.AutoscalingSpec{
+					MinReplicas:                    ptr.To(int32(2)),
+					MaxReplicas:                    5,
+					TargetCPUUtilizationPercentage: ptr.To(int32(70)),
+				}
+			})
+			reconcileSamtest()
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			getManaged(hpa)
+			Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal(resourceName))
+			Expect(hpa.Spec.MinReplicas).To(HaveValue(Equal(int32(2))))
+			Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+			Expect(hpa.Spec.Metrics).To(HaveLen(1))
+			Expect(hpa.Spec.Metrics[0].Resource.Target.AverageUtilization).To(HaveValue(Equal(int32(70))))
+
+			By("Scaling the Deployment as the autoscaler would")
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			deployment.Spec.Replicas = ptr.To(int32(4))
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+			hpa.Status.CurrentReplicas = 3
+			hpa.Status.DesiredReplicas = 4
+			Expect(k8sClient.Status().Update(ctx, hpa)).To(Succeed())
+
+			resource := reconcileSamtest()
+			Expect(resource.Status.Autoscaling).To(Equal(&cachev1alpha1.AutoscalingStatus{
+				CurrentReplicas: 3,
+				DesiredReplicas: 4,
+			}))
+			getManaged(deployment)
+			Expect(deployment.Spec.Replicas).To(HaveValue(Equal(int32(4))))
+
+			By("Disabling autoscaling")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Autoscaling = nil
+			})
+			reconcileSamtest()
+
+			getManaged(deployment)
+			Expect(deployment.Spec.Replicas).To(HaveValue(Equal(int32(1))))
+			expectNoManaged(&autoscalingv2.HorizontalPodAutoscaler{})
+		})
+
+		It("should keep the replicas when autoscaling is enabled alongside other changes", func() {
+			By("Scaling to three replicas")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Replicas = 3
+			})
+			reconcileSamtest()
+
+			By("Enabling autoscaling and changing the image in one update")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Image = "nginx:1.27"
+				samtest.Spec.Autoscaling = &cachev1alpha1.AutoscalingSpec{
+					MinReplicas: ptr.To(int32(2)),
+					MaxReplicas: 5,
+				}
+			})
+			reconcileSamtest()
+
+			// The replicas stay put until the autoscaler takes them over
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.27"))
+			Expect(deployment.Spec.Replicas).To(HaveValue(Equal(int32(3))))
+		})
+	})
+
+	Describe("the PodDisruptionBudget", func() {
+		It("should only render a PodDisruptionBudget for more than one replica", func() {
+			By("Setting a disruption budget on a single replica")
+			maxUnavailable := intstr.FromString("50%")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.DisruptionBudget = &cachev1alpha1.DisruptionBudgetSpec{MaxUnavailable: &maxUnavailable}
+			})
+			reconcileSamtest()
+			expectNoManaged(&policyv1.PodDisruptionBudget{})
+
+			By("Scaling to three replicas")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Replicas = 3
+			})
+			reconcileSamtest()
+
+			pdb := &policyv1.PodDisruptionBudget{}
+			getManaged(pdb)
+			Expect(pdb.Spec.MaxUnavailable).To(HaveValue(Equal(maxUnavailable)))
+			Expect(pdb.Spec.MinAvailable).To(BeNil())
+			Expect(pdb.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": resourceName}))
+
+			By("Scaling back to a single replica")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Replicas = 1
+			})
+			reconcileSamtest()
+			expectNoManaged(&policyv1.PodDisruptionBudget{})
+		})
+	})
+
+	Describe("scheduling", func() {
+		It("should schedule the pods as configured", func() {
+			By("Setting scheduling constraints and the default spread")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.NodeSelector = map[string]string{"kubernetes.io/os": "linux"}
+				samtest.Spec.Tolerations = []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "apps", Effect: corev1.TaintEffectNoSchedule},
+				}
+				samtest.Spec.PriorityClassName = "system-cluster-critical"
+				samtest.Spec.SpreadReplicas = true
+				samtest.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+					{
+						MaxSkew:           2,
+						TopologyKey:       corev1.LabelTopologyZone,
+						WhenUnsatisfiable: corev1.DoNotSchedule,
+						LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": resourceName}},
+					},
+				}
+			})
+			reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/os": "linux"}))
+			Expect(podSpec.Tolerations).To(HaveLen(1))
+			Expect(podSpec.PriorityClassName).To(Equal("system-cluster-critical"))
+			Expect(podSpec.TopologySpreadConstraints).To(HaveLen(2))
+			// The explicit zone constraint replaces the default one
+			Expect(podSpec.TopologySpreadConstraints[0].MaxSkew).To(Equal(int32(2)))
+			Expect(podSpec.TopologySpreadConstraints[1].TopologyKey).To(Equal(corev1.LabelHostname))
+			Expect(podSpec.TopologySpreadConstraints[1].WhenUnsatisfiable).To(Equal(corev1.ScheduleAnyway))
+			Expect(podSpec.TopologySpreadConstraints[1].LabelSelector.MatchLabels).To(Equal(map[string]string{"app": resourceName}))
+
+			By("Removing the node selector")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.NodeSelector = nil
+			})
+			reconcileSamtest()
+
+			getManaged(deployment)
+			Expect(deployment.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+		})
+	})
+
+	Describe("pod security", func() {
+		It("should apply restricted security defaults and check them against the namespace", func() {
+			By("Leaving the condition unreported without an enforced standard")
+			resource := reconcileSamtest()
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, "PodSecurityViolated")).To(BeNil())
+
+			By("Enforcing the restricted Pod Security Standard on the namespace")
+			namespace := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "default"}, namespace)).To(Succeed())
+			namespace.Labels["pod-security.kubernetes.io/enforce"] = "restricted"
+			Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "default"}, namespace)).To(Succeed())
+				delete(namespace.Labels, "pod-security.kubernetes.io/enforce")
+				Expect(k8sClient.Update(ctx, namespace)).To(Succeed())
+			})
+
+			resource = reconcileSamtest()
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, "PodSecurityViolated")).To(BeTrue())
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.SecurityContext.RunAsNonRoot).To(HaveValue(BeTrue()))
+			Expect(podSpec.SecurityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeRuntimeDefault))
+			containerContext := podSpec.Containers[0].SecurityContext
+			Expect(containerContext.AllowPrivilegeEscalation).To(HaveValue(BeFalse()))
+			Expect(containerContext.ReadOnlyRootFilesystem).To(HaveValue(BeTrue()))
+			Expect(containerContext.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+
+			By("Overriding the defaults with a writable root filesystem")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.SecurityContext = &cachev1alpha1.SecurityContextSpec{
+					Container: &corev1.SecurityContext{ReadOnlyRootFilesystem: ptr.To(false)},
+				}
 			})
+			reconcileSamtest()
+
+			getManaged(deployment)
+			containerContext = deployment.Spec.Template.Spec.Containers[0].SecurityContext
+			Expect(containerContext.ReadOnlyRootFilesystem).To(HaveValue(BeFalse()))
+			Expect(containerContext.AllowPrivilegeEscalation).To(HaveValue(BeFalse()))
+
+			By("Overriding the defaults with a privileged container")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.SecurityContext.Container.Privileged = ptr.To(true)
+				samtest.Spec.SecurityContext.Container.AllowPrivilegeEscalation = ptr.To(true)
+			})
+			result, err := controllerReconciler.Reconcile(ctx, request)
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+			Expect(result.RequeueAfter).To(Equal(healthRequeueInterval))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "PodSecurityViolated")).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "Failed")).To(BeTrue())
+			violated := meta.FindStatusCondition(resource.Status.Conditions, "PodSecurityViolated")
+			Expect(violated.Message).To(ContainSubstring("restricted:latest"))
+			Expect(violated.Message).To(ContainSubstring("securityContext.privileged=true"))
+			Expect(violated.Message).To(ContainSubstring("securityContext.allowPrivilegeEscalation=false"))
+
+			// The violating pod template is never applied
+			getManaged(deployment)
+			Expect(deployment.Spec.Template.Spec.Containers[0].SecurityContext.Privileged).To(BeNil())
+		})
+	})
+
+	Describe("the ServiceAccount", func() {
+		It("should run the pods as a ServiceAccount of their own", func() {
+			By("Annotating the generated ServiceAccount")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.ServiceAccount = &cachev1alpha1.ServiceAccountSpec{
+					Annotations:                  map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/samtest"},
+					AutomountServiceAccountToken: ptr.To(false),
+				}
+			})
+			reconcileSamtest()
+
+			serviceAccount := &corev1.ServiceAccount{}
+			getManaged(serviceAccount)
+			Expect(serviceAccount.Annotations).To(HaveKeyWithValue("eks.amazonaws.com/role-arn", "arn:aws:iam::123456789012:role/samtest"))
+			Expect(serviceAccount.AutomountServiceAccountToken).To(HaveValue(BeFalse()))
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal(resourceName))
+			Expect(deployment.Spec.Template.Spec.AutomountServiceAccountToken).To(HaveValue(BeFalse()))
+
+			By("Removing the annotation and the token mount setting")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.ServiceAccount = &cachev1alpha1.ServiceAccountSpec{}
+			})
+			reconcileSamtest()
+
+			getManaged(serviceAccount)
+			Expect(serviceAccount.Annotations).NotTo(HaveKey("eks.amazonaws.com/role-arn"))
+			Expect(serviceAccount.AutomountServiceAccountToken).To(BeNil())
+			getManaged(deployment)
+			Expect(deployment.Spec.Template.Spec.AutomountServiceAccountToken).To(BeNil())
+
+			By("Referencing an existing ServiceAccount instead")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.ServiceAccount = &cachev1alpha1.ServiceAccountSpec{Name: "shared"}
+			})
+			reconcileSamtest()
+
+			getManaged(deployment)
+			Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("shared"))
+			expectNoManaged(&corev1.ServiceAccount{})
+		})
+	})
+
+	Describe("RBAC", func() {
+		It("should grant rules within the ceiling and refuse rules beyond it", func() {
+			By("Leaving the condition unreported without any rules")
+			resource := reconcileSamtest()
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, "RBACRefused")).To(BeNil())
+
+			By("Granting read access to ConfigMaps")
+			rules := []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list"}},
+			}
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.RBAC = &cachev1alpha1.RBACSpec{Rules: rules}
+			})
+			resource = reconcileSamtest()
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, "RBACRefused")).To(BeTrue())
+
+			role := &rbacv1.Role{}
+			getManaged(role)
+			Expect(role.Rules).To(Equal(rules))
+
+			binding := &rbacv1.RoleBinding{}
+			getManaged(binding)
+			Expect(binding.RoleRef.Name).To(Equal(resourceName))
+			Expect(binding.Subjects).To(ConsistOf(rbacv1.Subject{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      resourceName,
+				Namespace: "default",
+			}))
+
+			By("Granting read access to Secrets")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.RBAC.Rules = append(samtest.Spec.RBAC.Rules, rbacv1.PolicyRule{
+					APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"},
+				})
+			})
+			resource = reconcileSamtest()
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "RBACRefused")).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "Failed")).To(BeTrue())
+			refused := meta.FindStatusCondition(resource.Status.Conditions, "RBACRefused")
+			Expect(refused.Message).To(ContainSubstring("get secrets"))
+
+			// The refused rule is never granted
+			getManaged(role)
+			Expect(role.Rules).To(HaveLen(1))
+
+			By("Removing the rules")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.RBAC = nil
+			})
+			resource = reconcileSamtest()
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, "RBACRefused")).To(BeNil())
+		})
+	})
+
+	Describe("volumes and persistence", func() {
+		It("should mount volumes and a retained PersistentVolumeClaim", func() {
+			By("Configuring volumes and persistence")
+			storageClass := &storagev1.StorageClass{
+				ObjectMeta:           metav1.ObjectMeta{Name: "expandable"},
+				Provisioner:          "example.com/csi",
+				AllowVolumeExpansion: ptr.To(true),
+			}
+			Expect(k8sClient.Create(ctx, storageClass)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, storageClass))).To(Succeed())
+			})
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Volumes = []cachev1alpha1.Volume{
+					{Name: "config", ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "samtest-config"},
+					}},
+					{Name: "tmp", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				}
+				samtest.Spec.VolumeMounts = []corev1.VolumeMount{
+					{Name: "config", MountPath: "/etc/samtest", ReadOnly: true},
+					{Name: "tmp", MountPath: "/tmp"},
+				}
+				samtest.Spec.Persistence = &cachev1alpha1.PersistenceSpec{
+					Size:             k8sresource.MustParse("1Gi"),
+					StorageClassName: ptr.To("expandable"),
+					MountPath:        "/var/lib/samtest",
+					RetainOnDelete:   true,
+				}
+			})
+			resource := reconcileSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.Volumes).To(HaveLen(3))
+			Expect(podSpec.Volumes[2].PersistentVolumeClaim.ClaimName).To(Equal(resourceName))
+			Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name:      "data",
+				MountPath: "/var/lib/samtest",
+			}))
+			// The mounted ConfigMap is hashed, even though it does not exist yet
+			Expect(deployment.Spec.Template.Annotations).To(HaveKey(resources.ConfigChecksumAnnotation))
+			// The single replica releases the claim before its replacement starts
+			Expect(deployment.Spec.Strategy.Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
+			Expect(deployment.Spec.Strategy.RollingUpdate).To(BeNil())
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			getManaged(pvc)
+			Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("1Gi"))
+
+			By("Rejecting a smaller size, a storage class change and more replicas")
+			shrunk := resource.DeepCopy()
+			shrunk.Spec.Persistence.Size = k8sresource.MustParse("512Mi")
+			Expect(k8sClient.Update(ctx, shrunk)).NotTo(Succeed())
+			unclassed := resource.DeepCopy()
+			unclassed.Spec.Persistence.StorageClassName = nil
+			Expect(k8sClient.Update(ctx, unclassed)).NotTo(Succeed())
+			scaled := resource.DeepCopy()
+			scaled.Spec.Replicas = 3
+			Expect(k8sClient.Update(ctx, scaled)).NotTo(Succeed())
+
+			By("Expanding the bound claim")
+			pvc.Status.Phase = corev1.ClaimBound
+			Expect(k8sClient.Status().Update(ctx, pvc)).To(Succeed())
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Persistence.Size = k8sresource.MustParse("2Gi")
+			})
+			reconcileSamtest()
+
+			getManaged(pvc)
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+
+			By("Deleting the custom resource")
+			deleteSamtest()
+
+			getManaged(pvc)
+			Expect(pvc.OwnerReferences).To(BeEmpty())
+		})
+	})
+
+	Describe("readiness and status", func() {
+		It("should only report ready once every resource is healthy", func() {
+			result, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			resource := &cachev1alpha1.Samtest{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, "Ready")).To(BeTrue())
+
+			By("Simulating a completed rollout of the Deployment")
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			deployment.Status = appsv1.DeploymentStatus{
+				ObservedGeneration: deployment.Generation,
+				Replicas:           1,
+				UpdatedReplicas:    1,
+				ReadyReplicas:      1,
+				AvailableReplicas:  1,
+			}
+			Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+
+			By("Simulating a ready endpoint for the Service")
+			endpointSlice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-endpoints",
+					Namespace: "default",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: resourceName},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}}},
+			}
+			Expect(k8sClient.Create(ctx, endpointSlice)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, endpointSlice))).To(Succeed())
+			})
+
+			By("Reconciling the healthy resource")
+			result, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "Ready")).To(BeTrue())
+
+			By("Checking the observed state was reported")
+			Expect(resource.Status.ObservedGeneration).To(Equal(resource.Generation))
+			Expect(resource.Status.ReadyReplicas).To(Equal(int32(1)))
+			Expect(resource.Status.AvailableReplicas).To(Equal(int32(1)))
+			Expect(resource.Status.Image).To(Equal("nginx:latest"))
+			Expect(resource.Status.ClusterIP).NotTo(BeEmpty())
+			Expect(resource.Status.DNSName).To(Equal(resourceName + ".default.svc.cluster.local"))
+			Expect(resource.Status.LastReconcileTime).NotTo(BeNil())
+
+			By("Checking the sibling conditions were cleared")
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, "Progressing")).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, "Failed")).To(BeTrue())
+			for _, condition := range resource.Status.Conditions {
+				Expect(condition.ObservedGeneration).To(Equal(resource.Generation))
+			}
+		})
+	})
+
+	Describe("reporting failures", func() {
+		It("should report every resource which failed in the same pass", func() {
+			By("Creating a ServiceAccount and a Service whose labels another manager owns")
+			labels := map[string]string{"app": "other"}
+			serviceAccount := &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default", Labels: labels},
+			}
+			Expect(k8sClient.Create(ctx, serviceAccount, client.FieldOwner("other-controller"))).To(Succeed())
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default", Labels: labels},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Name: "other", Port: 9000}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, service, client.FieldOwner("other-controller"))).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, request)
+			Expect(err).To(HaveOccurred())
+
+			By("Checking the Failed condition names both resources in order")
+			resource := &cachev1alpha1.Samtest{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "Failed")).To(BeTrue())
+			failed := meta.FindStatusCondition(resource.Status.Conditions, "Failed")
+			Expect(failed.Message).To(MatchRegexp(`Service test-resource: .*ServiceAccount test-resource: `))
+
+			By("Checking the ReconcileError event names both resources in order")
+			var reconcileError string
+			events := controllerReconciler.Recorder.(*record.FakeRecorder).Events
+			for len(events) > 0 {
+				if event := <-events; strings.HasPrefix(event, "Warning ReconcileError ") {
+					reconcileError = event
+				}
+			}
+			Expect(reconcileError).To(MatchRegexp(`Service test-resource: .*ServiceAccount test-resource: `))
+
+			By("Checking the other resources were still applied")
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			Expect(metav1.IsControlledBy(deployment, resource)).To(BeTrue())
+		})
+	})
+
+	Describe("status updates", func() {
+		It("should retry a status write which conflicts with another writer", func() {
+			resource := reconcileSamtest()
+
+			By("Changing the stored Samtest between reading and patching its status")
+			reader := &conflictingReader{Reader: k8sClient}
+			controllerReconciler.APIReader = reader
+			Expect(controllerReconciler.updateStatus(ctx, resource, k8s.ResourcesFailed, "conflicting write")).To(Succeed())
+			Expect(reader.samtestReads).To(Equal(2))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Annotations).To(HaveKey("test/bumped"))
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, "Failed")).To(BeTrue())
+			failed := meta.FindStatusCondition(resource.Status.Conditions, "Failed")
+			Expect(failed.Message).To(Equal("conflicting write"))
+		})
+
+		It("should leave the Samtest untouched when nothing changed", func() {
+			resource := reconcileSamtest()
+			resourceVersion := resource.ResourceVersion
+
+			resource = reconcileSamtest()
+			Expect(resource.ResourceVersion).To(Equal(resourceVersion))
+		})
+	})
+
+	Describe("pruning", func() {
+		It("should prune stale resources controlled by the Samtest", func() {
+			resource := &cachev1alpha1.Samtest{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			By("Creating a stale resource controlled by the Samtest and one which is not")
+			owned := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-owned", Namespace: "default"}}
+			Expect(controllerutil.SetControllerReference(resource, owned, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, owned)).To(Succeed())
+			foreign := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-foreign", Namespace: "default"}}
+			Expect(k8sClient.Create(ctx, foreign)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, owned))).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, foreign))).To(Succeed())
+			})
+
+			By("Recording both resources in the inventory")
+			resource.Status.Inventory = []cachev1alpha1.ResourceReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: owned.Name},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: foreign.Name},
+			}
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			resource = reconcileSamtest()
+			Expect(resource.Status.Inventory).To(ConsistOf(
+				cachev1alpha1.ResourceReference{APIVersion: "v1", Kind: "ServiceAccount", Name: resourceName},
+				cachev1alpha1.ResourceReference{APIVersion: "apps/v1", Kind: "Deployment", Name: resourceName},
+				cachev1alpha1.ResourceReference{APIVersion: "v1", Kind: "Service", Name: resourceName},
+			))
+
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(owned), &corev1.ConfigMap{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(foreign), &corev1.ConfigMap{})).To(Succeed())
+		})
+
+		It("should drop stale resources of a kind which is no longer served", func() {
+			By("Recording a resource of an unknown kind in the inventory")
+			resource := &cachev1alpha1.Samtest{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Status.Inventory = []cachev1alpha1.ResourceReference{
+				{APIVersion: "example.com/v1", Kind: "Widget", Name: resourceName},
+			}
+			Expect(k8sClient.Status().Update(ctx, resource)).To(Succeed())
+
+			resource = reconcileSamtest()
+			Expect(resource.Status.Inventory).NotTo(ContainElement(HaveField("Kind", "Widget")))
+		})
+	})
+
+	Describe("deletion", func() {
+		It("should tear down the managed resources on deletion", func() {
+			resource := reconcileSamtest()
+			Expect(resource.Finalizers).To(ContainElement(samtestFinalizer))
+
+			By("Deleting the custom resource")
+			deleteSamtest()
+
+			expectNoManaged(&appsv1.Deployment{})
+			expectNoManaged(&corev1.Service{})
+		})
+
+		It("should tear down resources dropped from the spec before deletion", func() {
+			By("Enabling autoscaling")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Autoscaling = &cachev1alpha1.AutoscalingSpec{MaxReplicas: 3}
+			})
+			resource := reconcileSamtest()
+			Expect(resource.Status.Inventory).To(ContainElement(HaveField("Kind", "HorizontalPodAutoscaler")))
+			getManaged(&autoscalingv2.HorizontalPodAutoscaler{})
+
+			By("Disabling autoscaling and deleting the custom resource before it is reconciled")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.Autoscaling = nil
+			})
+			deleteSamtest()
+
+			expectNoManaged(&autoscalingv2.HorizontalPodAutoscaler{})
+			expectNoManaged(&appsv1.Deployment{})
+		})
+
+		It("should release the managed resources on deletion with the Orphan policy", func() {
+			By("Setting the Orphan deletion policy")
+			updateSamtest(func(samtest *cachev1alpha1.Samtest) {
+				samtest.Spec.DeletionPolicy = cachev1alpha1.DeletionPolicyOrphan
+			})
+			reconcileSamtest()
+
+			By("Deleting the custom resource")
+			deleteSamtest()
+
+			deployment := &appsv1.Deployment{}
+			getManaged(deployment)
+			Expect(deployment.OwnerReferences).To(BeEmpty())
 		})
 	})
 })
+
+// A client.Reader which changes the stored Samtest after the first time it is
+// read, so the status patch built from that read conflicts.
+type conflictingReader struct {
+	client.Reader
+	samtestReads int
+}
+
+func (r *conflictingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := r.Reader.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	samtest, ok := obj.(*cachev1alpha1.Samtest)
+	if !ok {
+		return nil
+	}
+	r.samtestReads++
+	if r.samtestReads > 1 {
+		return nil
+	}
+
+	bumped := samtest.DeepCopy()
+	if bumped.Annotations == nil {
+		bumped.Annotations = map[string]string{}
+	}
+	bumped.Annotations["test/bumped"] = "true"
+	return k8sClient.Update(ctx, bumped)
+}
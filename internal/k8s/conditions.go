@@ -1,6 +1,9 @@
 package k8s
 
 import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -9,6 +12,7 @@ type ConditionReason int
 
 type Condition struct {
 	conditionType ConditionType
+	status        metav1.ConditionStatus
 	reason        string
 	message       string
 }
@@ -17,36 +21,106 @@ const (
 	ConditionReady ConditionType = iota
 	ConditionProgressing
 	ConditionFailed
+	ConditionConflicted
+	ConditionTerminating
+	ConditionPodSecurityViolated
+	ConditionRBACRefused
 )
 
 const (
 	ResourcesReady ConditionReason = iota
 	ProgressingResources
 	ResourcesFailed
+	FieldConflict
+	NoFieldConflicts
+	TearingDown
+	PodSecurityViolation
+	PodSecurityCompliant
+	RulesExceedCeiling
+	RulesWithinCeiling
 )
 
 var ConditionTypeMap = map[ConditionType]string{
 	ConditionReady:       "Ready",
 	ConditionProgressing: "Progressing",
 	ConditionFailed:      "Failed",
+	ConditionConflicted:  "Conflicted",
+	ConditionTerminating: "Terminating",
+
+	ConditionPodSecurityViolated: "PodSecurityViolated",
+	ConditionRBACRefused:         "RBACRefused",
+}
+
+// The lifecycle conditions are mutually exclusive, only one of them can be
+// True at any time.
+var lifecycleConditions = []ConditionType{
+	ConditionReady,
+	ConditionProgressing,
+	ConditionFailed,
+	ConditionTerminating,
 }
 
 var conditionReasonMap = map[ConditionReason]Condition{
 	ResourcesReady: {
 		conditionType: ConditionReady,
+		status:        metav1.ConditionTrue,
 		reason:        "ResourcesReady",
 		message:       "Recources all ready and in desired state",
 	},
 	ProgressingResources: {
 		conditionType: ConditionProgressing,
+		status:        metav1.ConditionTrue,
 		reason:        "ProgressingResources",
 		message:       "Progressing resources to sync with the desired state",
 	},
 	ResourcesFailed: {
 		conditionType: ConditionFailed,
+		status:        metav1.ConditionTrue,
 		reason:        "ResourcesFailed",
 		message:       "Failed to provision resources",
 	},
+	FieldConflict: {
+		conditionType: ConditionConflicted,
+		status:        metav1.ConditionTrue,
+		reason:        "FieldConflict",
+		message:       "Fields of managed resources are owned by another field manager",
+	},
+	NoFieldConflicts: {
+		conditionType: ConditionConflicted,
+		status:        metav1.ConditionFalse,
+		reason:        "NoFieldConflicts",
+		message:       "All fields of managed resources were applied",
+	},
+	TearingDown: {
+		conditionType: ConditionTerminating,
+		status:        metav1.ConditionTrue,
+		reason:        "TearingDown",
+		message:       "Tearing down managed resources",
+	},
+	PodSecurityViolation: {
+		conditionType: ConditionPodSecurityViolated,
+		status:        metav1.ConditionTrue,
+		reason:        "PodSecurityViolation",
+		message:       "Pods violate the Pod Security Standard enforced on the namespace",
+	},
+	PodSecurityCompliant: {
+		conditionType: ConditionPodSecurityViolated,
+		status:        metav1.ConditionFalse,
+		reason:        "PodSecurityCompliant",
+		message:       "Pods comply with the Pod Security Standard enforced on the namespace",
+	},
+	RulesExceedCeiling: {
+		conditionType: ConditionRBACRefused,
+		status:        metav1.ConditionTrue,
+		reason:        "RulesExceedCeiling",
+		message:       "RBAC rules grant more than the ceiling configured on the operator",
+	},
+	RulesWithinCeiling: {
+		conditionType: ConditionRBACRefused,
+		status:        metav1.ConditionFalse,
+		reason:        "RulesWithinCeiling",
+		message:       "RBAC rules are within the ceiling configured on the operator",
+	},
 }
 
 // Creates a condition status for the CRD using a provided ConditionReason.
@@ -55,8 +129,79 @@ var conditionReasonMap = map[ConditionReason]Condition{
 func NewStatusCondition(reason ConditionReason) metav1.Condition {
 	return metav1.Condition{
 		Type:    ConditionTypeMap[conditionReasonMap[reason].conditionType],
-		Status:  metav1.ConditionTrue,
+		Status:  conditionReasonMap[reason].status,
 		Reason:  conditionReasonMap[reason].reason,
 		Message: conditionReasonMap[reason].message,
 	}
 }
+
+// ConditionManager sets conditions on the status of a CRD, keeping the
+// lifecycle conditions mutually exclusive and stamping the generation they
+// were observed at.
+type ConditionManager struct {
+	conditions *[]metav1.Condition
+	generation int64
+}
+
+// NewConditionManager creates a ConditionManager for the conditions of a CRD
+// observed at the provided generation.
+func NewConditionManager(conditions *[]metav1.Condition, generation int64) *ConditionManager {
+	return &ConditionManager{
+		conditions: conditions,
+		generation: generation,
+	}
+}
+
+// Initialize sets every lifecycle condition which has not yet been reported
+// to Unknown, so a freshly created CRD shows each condition.
+func (m *ConditionManager) Initialize() {
+	for _, conditionType := range lifecycleConditions {
+		if meta.FindStatusCondition(*m.conditions, ConditionTypeMap[conditionType]) != nil {
+			continue
+		}
+		meta.SetStatusCondition(m.conditions, metav1.Condition{
+			Type:               ConditionTypeMap[conditionType],
+			Status:             metav1.ConditionUnknown,
+			Reason:             "Reconciling",
+			Message:            "Reconciliation has not yet completed",
+			ObservedGeneration: m.generation,
+		})
+	}
+}
+
+// Set sets the condition for a ConditionReason. The message overrides the
+// default message of the reason, unless empty. When a lifecycle condition is
+// set True, every other lifecycle condition is set False with the same reason
+// and message.
+func (m *ConditionManager) Set(reason ConditionReason, message string) {
+	condition := NewStatusCondition(reason)
+	condition.ObservedGeneration = m.generation
+	if message != "" {
+		condition.Message = message
+	}
+	meta.SetStatusCondition(m.conditions, condition)
+
+	conditionType := conditionReasonMap[reason].conditionType
+	if condition.Status != metav1.ConditionTrue || !slices.Contains(lifecycleConditions, conditionType) {
+		return
+	}
+
+	for _, sibling := range lifecycleConditions {
+		if sibling == conditionType {
+			continue
+		}
+		meta.SetStatusCondition(m.conditions, metav1.Condition{
+			Type:               ConditionTypeMap[sibling],
+			Status:             metav1.ConditionFalse,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			ObservedGeneration: m.generation,
+		})
+	}
+}
+
+// Remove removes the condition of a ConditionType, for a check which no longer
+// applies to the CRD.
+func (m *ConditionManager) Remove(conditionType ConditionType) {
+	meta.RemoveStatusCondition(m.conditions, ConditionTypeMap[conditionType])
+}
@@ -0,0 +1,174 @@
+package k8s
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConditionManagerSet(t *testing.T) {
+	const generation = 3
+
+	tests := []struct {
+		name        string
+		existing    []metav1.Condition
+		reason      ConditionReason
+		message     string
+		want        map[string]metav1.ConditionStatus
+		wantMessage string
+	}{
+		{
+			name:   "lifecycle condition set on an empty status",
+			reason: ResourcesReady,
+			want: map[string]metav1.ConditionStatus{
+				"Ready":       metav1.ConditionTrue,
+				"Progressing": metav1.ConditionFalse,
+				"Failed":      metav1.ConditionFalse,
+				"Terminating": metav1.ConditionFalse,
+			},
+			wantMessage: "Recources all ready and in desired state",
+		},
+		{
+			name: "lifecycle condition replacing another",
+			existing: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ResourcesReady", ObservedGeneration: 1},
+				{Type: "Progressing", Status: metav1.ConditionFalse, Reason: "ResourcesReady", ObservedGeneration: 1},
+				{Type: "Failed", Status: metav1.ConditionFalse, Reason: "ResourcesReady", ObservedGeneration: 1},
+				{Type: "Terminating", Status: metav1.ConditionFalse, Reason: "ResourcesReady", ObservedGeneration: 1},
+			},
+			reason:  ResourcesFailed,
+			message: "Deployment test: invalid",
+			want: map[string]metav1.ConditionStatus{
+				"Ready":       metav1.ConditionFalse,
+				"Progressing": metav1.ConditionFalse,
+				"Failed":      metav1.ConditionTrue,
+				"Terminating": metav1.ConditionFalse,
+			},
+			wantMessage: "Deployment test: invalid",
+		},
+		{
+			name: "independent condition leaving the lifecycle conditions",
+			existing: []metav1.Condition{
+				{Type: "Progressing", Status: metav1.ConditionTrue, Reason: "ProgressingResources", ObservedGeneration: generation},
+			},
+			reason: FieldConflict,
+			want: map[string]metav1.ConditionStatus{
+				"Progressing": metav1.ConditionTrue,
+				"Conflicted":  metav1.ConditionTrue,
+			},
+			wantMessage: "Fields of managed resources are owned by another field manager",
+		},
+		{
+			name:   "independent condition set False",
+			reason: RulesWithinCeiling,
+			want: map[string]metav1.ConditionStatus{
+				"RBACRefused": metav1.ConditionFalse,
+			},
+			wantMessage: "RBAC rules are within the ceiling configured on the operator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions := tt.existing
+			NewConditionManager(&conditions, generation).Set(tt.reason, tt.message)
+
+			if len(conditions) != len(tt.want) {
+				t.Errorf("Set() left %d conditions, want %d", len(conditions), len(tt.want))
+			}
+			for conditionType, status := range tt.want {
+				condition := meta.FindStatusCondition(conditions, conditionType)
+				if condition == nil {
+					t.Errorf("Set() did not report %s", conditionType)
+					continue
+				}
+				if condition.Status != status {
+					t.Errorf("Set() %s = %s, want %s", conditionType, condition.Status, status)
+				}
+				if condition.ObservedGeneration != generation {
+					t.Errorf("Set() %s observed generation = %d, want %d", conditionType, condition.ObservedGeneration, generation)
+				}
+			}
+
+			set := meta.FindStatusCondition(conditions, ConditionTypeMap[conditionReasonMap[tt.reason].conditionType])
+			if set.Message != tt.wantMessage {
+				t.Errorf("Set() message = %q, want %q", set.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestConditionManagerInitialize(t *testing.T) {
+	const generation = 2
+
+	tests := []struct {
+		name     string
+		existing []metav1.Condition
+		want     map[string]metav1.ConditionStatus
+	}{
+		{
+			name: "empty status",
+			want: map[string]metav1.ConditionStatus{
+				"Ready":       metav1.ConditionUnknown,
+				"Progressing": metav1.ConditionUnknown,
+				"Failed":      metav1.ConditionUnknown,
+				"Terminating": metav1.ConditionUnknown,
+			},
+		},
+		{
+			name: "reported conditions kept",
+			existing: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ResourcesReady", ObservedGeneration: generation},
+				{Type: "Failed", Status: metav1.ConditionFalse, Reason: "ResourcesReady", ObservedGeneration: generation},
+				{Type: "RBACRefused", Status: metav1.ConditionFalse, Reason: "RulesWithinCeiling", ObservedGeneration: generation},
+			},
+			want: map[string]metav1.ConditionStatus{
+				"Ready":       metav1.ConditionTrue,
+				"Progressing": metav1.ConditionUnknown,
+				"Failed":      metav1.ConditionFalse,
+				"Terminating": metav1.ConditionUnknown,
+				"RBACRefused": metav1.ConditionFalse,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions := tt.existing
+			NewConditionManager(&conditions, generation).Initialize()
+
+			if len(conditions) != len(tt.want) {
+				t.Errorf("Initialize() left %d conditions, want %d", len(conditions), len(tt.want))
+			}
+			for conditionType, status := range tt.want {
+				condition := meta.FindStatusCondition(conditions, conditionType)
+				if condition == nil {
+					t.Errorf("Initialize() did not report %s", conditionType)
+					continue
+				}
+				if condition.Status != status {
+					t.Errorf("Initialize() %s = %s, want %s", conditionType, condition.Status, status)
+				}
+				if condition.ObservedGeneration != generation {
+					t.Errorf("Initialize() %s observed generation = %d, want %d", conditionType, condition.ObservedGeneration, generation)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionManagerRemove(t *testing.T) {
+	conditions := []metav1.Condition{
+		{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ResourcesReady"},
+		{Type: "PodSecurityViolated", Status: metav1.ConditionFalse, Reason: "PodSecurityCompliant"},
+	}
+	NewConditionManager(&conditions, 1).Remove(ConditionPodSecurityViolated)
+
+	if meta.FindStatusCondition(conditions, "PodSecurityViolated") != nil {
+		t.Errorf("Remove() left PodSecurityViolated")
+	}
+	if meta.FindStatusCondition(conditions, "Ready") == nil {
+		t.Errorf("Remove() removed Ready")
+	}
+}
@@ -77,3 +77,49 @@ func NewOutOfSyncEvent(crd runtime.Object, recorder record.EventRecorder, kind s
 		Message:   fmt.Sprintf("%s %s is out of sync with the desired spec", kind, name),
 	})
 }
+
+// NewConflictEvent creates a new Kubernetes resource field ownership conflict event on the CRD.
+func NewConflictEvent(crd runtime.Object, recorder record.EventRecorder, kind string, name string, err error) {
+	NewEvent(crd, recorder, Event{
+		EventType: EventTypeWarning,
+		Reason:    kind + "Conflict",
+		Message:   fmt.Sprintf("%s %s has fields owned by another field manager: %v", kind, name, err),
+	})
+}
+
+// NewDeletedEvent creates a new Kubernetes resource deleted event on the CRD.
+func NewDeletedEvent(crd runtime.Object, recorder record.EventRecorder, kind string, name string) {
+	NewEvent(crd, recorder, Event{
+		EventType: EventTypeNormal,
+		Reason:    kind + "Deleted",
+		Message:   fmt.Sprintf("%s %s has been deleted successfully", kind, name),
+	})
+}
+
+// NewDeleteErrorEvent creates a new Kubernetes resource deletion error event on the CRD.
+func NewDeleteErrorEvent(crd runtime.Object, recorder record.EventRecorder, kind string, name string) {
+	NewEvent(crd, recorder, Event{
+		EventType: EventTypeWarning,
+		Reason:    kind + "DeleteError",
+		Message:   fmt.Sprintf("An error occurred whilst deleting %s %s", kind, name),
+	})
+}
+
+// NewOrphanedEvent creates a new Kubernetes resource orphaned event on the CRD.
+func NewOrphanedEvent(crd runtime.Object, recorder record.EventRecorder, kind string, name string) {
+	NewEvent(crd, recorder, Event{
+		EventType: EventTypeNormal,
+		Reason:    kind + "Orphaned",
+		Message:   fmt.Sprintf("%s %s has been released and will not be deleted", kind, name),
+	})
+}
+
+// NewReconcileErrorEvent creates a new reconciliation error event on the CRD, listing
+// every resource which failed to reconcile.
+func NewReconcileErrorEvent(crd runtime.Object, recorder record.EventRecorder, err error) {
+	NewEvent(crd, recorder, Event{
+		EventType: EventTypeWarning,
+		Reason:    "ReconcileError",
+		Message:   fmt.Sprintf("Failed to reconcile resources: %v", err),
+	})
+}
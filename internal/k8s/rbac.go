@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// ExceedingPermissions returns every permission granted by the rules which no
+// rule of the ceiling grants, described as a verb on a resource. An empty
+// result means the rules stay within the ceiling.
+func ExceedingPermissions(ceiling []rbacv1.PolicyRule, rules []rbacv1.PolicyRule) []string {
+	var exceeding []string
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					covered := slices.ContainsFunc(ceiling, func(ceilingRule rbacv1.PolicyRule) bool {
+						return coversPermission(ceilingRule, group, resource, verb, rule.ResourceNames)
+					})
+					if covered {
+						continue
+					}
+
+					permission := fmt.Sprintf("%s %s", verb, qualifiedResource(group, resource))
+					if len(rule.ResourceNames) > 0 {
+						permission += fmt.Sprintf(" named %s", strings.Join(rule.ResourceNames, ", "))
+					}
+					if !slices.Contains(exceeding, permission) {
+						exceeding = append(exceeding, permission)
+					}
+				}
+			}
+		}
+		for _, url := range rule.NonResourceURLs {
+			exceeding = append(exceeding, fmt.Sprintf("non-resource URL %s", url))
+		}
+	}
+	return exceeding
+}
+
+// Checks whether a ceiling rule grants a verb on a resource, limited to the
+// resource names when any are given. A wildcard in the ceiling covers
+// anything, whereas a wildcard in the checked rule is only covered by a
+// wildcard.
+func coversPermission(ceiling rbacv1.PolicyRule, group string, resource string, verb string, resourceNames []string) bool {
+	if !matchesOrWildcard(ceiling.APIGroups, group) || !matchesOrWildcard(ceiling.Verbs, verb) {
+		return false
+	}
+
+	coversResource := slices.ContainsFunc(ceiling.Resources, func(ceilingResource string) bool {
+		if ceilingResource == rbacv1.ResourceAll || ceilingResource == resource {
+			return true
+		}
+		// A ceiling of resource/* covers every subresource of the resource
+		parent, found := strings.CutSuffix(ceilingResource, "/*")
+		return found && strings.HasPrefix(resource, parent+"/")
+	})
+	if !coversResource {
+		return false
+	}
+
+	if len(ceiling.ResourceNames) == 0 {
+		return true
+	}
+	return len(resourceNames) > 0 && !slices.ContainsFunc(resourceNames, func(name string) bool {
+		return !slices.Contains(ceiling.ResourceNames, name)
+	})
+}
+
+func matchesOrWildcard(values []string, value string) bool {
+	return slices.Contains(values, value) || slices.Contains(values, "*")
+}
+
+// Returns the resource qualified by its API group, as kubectl shows it.
+func qualifiedResource(group string, resource string) string {
+	if group == "" {
+		return resource
+	}
+	return resource + "." + group
+}
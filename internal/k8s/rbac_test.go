@@ -0,0 +1,156 @@
+package k8s
+
+import (
+	"slices"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestExceedingPermissions(t *testing.T) {
+	configMapReader := rbacv1.PolicyRule{
+		APIGroups: []string{""},
+		Resources: []string{"configmaps"},
+		Verbs:     []string{"get", "list", "watch"},
+	}
+
+	tests := []struct {
+		name    string
+		ceiling []rbacv1.PolicyRule
+		rules   []rbacv1.PolicyRule
+		want    []string
+	}{
+		{
+			name:    "rule matching the ceiling",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+		},
+		{
+			name:    "verb beyond the ceiling",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "delete"}}},
+			want:    []string{"delete configmaps"},
+		},
+		{
+			name:    "resource beyond the ceiling",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+			want:    []string{"get secrets"},
+		},
+		{
+			name:    "group beyond the ceiling",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+			want:    []string{"get configmaps.apps"},
+		},
+		{
+			name:    "rule verb wildcard against specific ceiling verbs",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"*"}}},
+			want:    []string{"* configmaps"},
+		},
+		{
+			name:    "rule resource wildcard against specific ceiling resources",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get"}}},
+			want:    []string{"get *"},
+		},
+		{
+			name:    "rule group wildcard against specific ceiling groups",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+			want:    []string{"get configmaps.*"},
+		},
+		{
+			name:    "ceiling wildcards covering specific rules",
+			ceiling: []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"", "apps"}, Resources: []string{"secrets", "deployments"}, Verbs: []string{"delete"}},
+				{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			},
+		},
+		{
+			name:    "ceiling subresource wildcard covering a subresource",
+			ceiling: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods/*"}, Verbs: []string{"get"}}},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}}},
+		},
+		{
+			name:    "ceiling subresource wildcard against the parent resource",
+			ceiling: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods/*"}, Verbs: []string{"get"}}},
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+			want:    []string{"get pods"},
+		},
+		{
+			name: "ceiling resource names covering a named rule",
+			ceiling: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"a", "b"},
+			}},
+			rules: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"a"},
+			}},
+		},
+		{
+			name: "ceiling resource names against an unnamed rule",
+			ceiling: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"a"},
+			}},
+			rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+			want:  []string{"get configmaps"},
+		},
+		{
+			name: "ceiling resource names against another name",
+			ceiling: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"a"},
+			}},
+			rules: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"a", "c"},
+			}},
+			want: []string{"get configmaps named a, c"},
+		},
+		{
+			name:    "unnamed ceiling covering a named rule",
+			ceiling: []rbacv1.PolicyRule{configMapReader},
+			rules: []rbacv1.PolicyRule{{
+				APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}, ResourceNames: []string{"a"},
+			}},
+		},
+		{
+			name:    "rules without verbs or groups grant nothing",
+			ceiling: nil,
+			rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"secrets"}},
+				{Resources: []string{"secrets"}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			name:    "empty ceiling refusing everything",
+			ceiling: nil,
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+			want:    []string{"get configmaps"},
+		},
+		{
+			name:    "non-resource URLs",
+			ceiling: []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			rules:   []rbacv1.PolicyRule{{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}}},
+			want:    []string{"non-resource URL /metrics"},
+		},
+		{
+			name:    "repeated permissions reported once",
+			ceiling: nil,
+			rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+			},
+			want: []string{"get secrets"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExceedingPermissions(tt.ceiling, tt.rules)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExceedingPermissions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
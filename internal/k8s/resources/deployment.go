@@ -1,21 +1,75 @@
 package resources
 
 import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
 	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
 )
 
+// The name of the container running the Samtest image.
+const mainContainerName = "main"
+
+// ConfigChecksumAnnotation is stamped on the pod template with a hash of the
+// ConfigMaps and Secrets referenced by the Samtest, rolling the Deployment when
+// their data changes.
+const ConfigChecksumAnnotation = "cache.k8s.capitalontap.com/config-checksum"
+
+// The prefix of the environment variables injected by the operator, which
+// the Samtest CRD validation reserves.
+const reservedEnvPrefix = "SAMTEST_"
+
 type Deployment struct {
 	Name      string
 	Namespace string
 	Image     string
+	Replicas  *int32
+	Strategy  appsv1.DeploymentStrategy
+	Resources corev1.ResourceRequirements
+	Env       []corev1.EnvVar
+	EnvFrom   []corev1.EnvFromSource
+	Ports     []cachev1alpha1.Port
 	Labels    k8s.Labels
+
+	LivenessProbe  *corev1.Probe
+	ReadinessProbe *corev1.Probe
+	StartupProbe   *corev1.Probe
+
+	NodeSelector              map[string]string
+	Tolerations               []corev1.Toleration
+	Affinity                  *corev1.Affinity
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint
+	SpreadReplicas            bool
+	PriorityClassName         string
+
+	PodSecurityContext       *corev1.PodSecurityContext
+	ContainerSecurityContext *corev1.SecurityContext
+
+	ServiceAccountName           string
+	AutomountServiceAccountToken *bool
+
+	Volumes      []corev1.Volume
+	VolumeMounts []corev1.VolumeMount
+
+	// ResourceProfiles are the named compute resources configured on the
+	// operator, which a Samtest can select by name.
+	ResourceProfiles map[string]corev1.ResourceRequirements
+
+	// ConfigChecksum is the hash of the configuration referenced by the
+	// Samtest, left empty when nothing is referenced.
+	ConfigChecksum string
 }
 
 // New creates a new Deployment with default values
@@ -24,7 +78,36 @@ func (d *Deployment) New(crd *cachev1alpha1.Samtest) Resource {
 		Name:      crd.Name,
 		Namespace: crd.Namespace,
 		Image:     crd.Spec.Image,
+		Replicas:  desiredReplicas(crd),
+		Strategy:  deploymentStrategy(crd),
+		Resources: mergeResources(d.ResourceProfiles[string(crd.Spec.ResourceProfile)], crd.Spec.Resources),
+		Env:       crd.Spec.Env,
+		EnvFrom:   crd.Spec.EnvFrom,
+		Ports:     samtestPorts(crd),
 		Labels:    k8s.CreateLabels(crd.Name),
+
+		LivenessProbe:  crd.Spec.LivenessProbe.DeepCopy(),
+		ReadinessProbe: crd.Spec.ReadinessProbe.DeepCopy(),
+		StartupProbe:   crd.Spec.StartupProbe.DeepCopy(),
+
+		NodeSelector:              maps.Clone(crd.Spec.NodeSelector),
+		Tolerations:               slices.Clone(crd.Spec.Tolerations),
+		Affinity:                  crd.Spec.Affinity.DeepCopy(),
+		TopologySpreadConstraints: slices.Clone(crd.Spec.TopologySpreadConstraints),
+		SpreadReplicas:            crd.Spec.SpreadReplicas,
+		PriorityClassName:         crd.Spec.PriorityClassName,
+
+		PodSecurityContext:       podSecurityContext(crd),
+		ContainerSecurityContext: containerSecurityContext(crd),
+
+		ServiceAccountName:           serviceAccountName(crd),
+		AutomountServiceAccountToken: automountServiceAccountToken(crd),
+
+		Volumes:      podVolumes(crd),
+		VolumeMounts: containerVolumeMounts(crd),
+
+		ResourceProfiles: d.ResourceProfiles,
+		ConfigChecksum:   d.ConfigChecksum,
 	}
 }
 
@@ -46,24 +129,43 @@ func (d *Deployment) Generate() client.Object {
 			Labels:    d.Labels,
 		},
 		Spec: appsv1.DeploymentSpec{
+			Replicas: d.Replicas,
+			Strategy: *d.Strategy.DeepCopy(),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: d.Labels,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: d.Labels,
+					Labels:      d.Labels,
+					Annotations: d.podAnnotations(),
 				},
 				Spec: corev1.PodSpec{
+					NodeSelector:              d.NodeSelector,
+					Tolerations:               d.Tolerations,
+					Affinity:                  d.Affinity,
+					TopologySpreadConstraints: d.topologySpreadConstraints(),
+					PriorityClassName:         d.PriorityClassName,
+					SecurityContext:           d.PodSecurityContext.DeepCopy(),
+
+					ServiceAccountName:           d.ServiceAccountName,
+					AutomountServiceAccountToken: d.AutomountServiceAccountToken,
+
+					Volumes: d.Volumes,
 					Containers: []corev1.Container{
 						{
-							Name:  "main",
-							Image: d.Image,
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "http",
-									ContainerPort: 80,
-								},
-							},
+							Name:      mainContainerName,
+							Image:     d.Image,
+							Resources: *d.Resources.DeepCopy(),
+							Env:       d.containerEnv(),
+							EnvFrom:   d.containerEnvFrom(),
+							Ports:     containerPorts(d.Ports),
+
+							LivenessProbe:  d.LivenessProbe.DeepCopy(),
+							ReadinessProbe: d.readinessProbe(),
+							StartupProbe:   d.StartupProbe.DeepCopy(),
+
+							SecurityContext: d.ContainerSecurityContext.DeepCopy(),
+							VolumeMounts:    d.VolumeMounts,
 						},
 					},
 				},
@@ -72,11 +174,284 @@ func (d *Deployment) Generate() client.Object {
 	}
 }
 
+// Checks whether the found Deployment matches the desired spec. Fields left unset
+// in the desired spec are ignored, so values defaulted by the API server do
+// not register as drift.
 func (d *Deployment) IsEqual(found client.Object) bool {
 	foundDeployment, ok := found.(*appsv1.Deployment)
 	if !ok {
 		return false
 	}
 
-	return equality.Semantic.DeepEqual(d.Generate().(*appsv1.Deployment).Spec, foundDeployment.Spec)
+	desired := d.Generate().(*appsv1.Deployment)
+	return equality.Semantic.DeepDerivative(desired.Spec, foundDeployment.Spec)
+}
+
+// Checks whether the found Deployment has rolled out its latest spec, with
+// every replica updated and available.
+func (d *Deployment) IsHealthy(_ context.Context, _ client.Reader, found client.Object) (bool, string, error) {
+	foundDeployment, ok := found.(*appsv1.Deployment)
+	if !ok {
+		return false, "", fmt.Errorf("expected a Deployment, got %T", found)
+	}
+
+	replicas := ptr.Deref(foundDeployment.Spec.Replicas, 1)
+	status := foundDeployment.Status
+
+	switch {
+	case status.ObservedGeneration < foundDeployment.Generation:
+		return false, fmt.Sprintf("Deployment %s is waiting for its latest spec to be observed", d.Name), nil
+	case status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("Deployment %s has %d of %d replicas updated", d.Name, status.UpdatedReplicas, replicas), nil
+	case status.Replicas > status.UpdatedReplicas:
+		return false, fmt.Sprintf("Deployment %s has %d old replicas pending termination",
+			d.Name, status.Replicas-status.UpdatedReplicas), nil
+	case status.AvailableReplicas < replicas:
+		return false, fmt.Sprintf("Deployment %s has %d of %d replicas available", d.Name, status.AvailableReplicas, replicas), nil
+	}
+
+	return true, "", nil
+}
+
+// Reports the scale and image of the found Deployment on the Samtest status.
+func (d *Deployment) ReportStatus(found client.Object, status *cachev1alpha1.SamtestStatus) {
+	foundDeployment, ok := found.(*appsv1.Deployment)
+	if !ok {
+		return
+	}
+
+	status.Replicas = foundDeployment.Status.Replicas
+	status.ReadyReplicas = foundDeployment.Status.ReadyReplicas
+	status.AvailableReplicas = foundDeployment.Status.AvailableReplicas
+	status.Selector = labels.SelectorFromSet(labels.Set(d.Labels)).String()
+	status.QOSClass = qosClass(d.Resources)
+
+	for _, container := range foundDeployment.Spec.Template.Spec.Containers {
+		if container.Name == mainContainerName {
+			status.Image = container.Image
+		}
+	}
+}
+
+// Returns the fields taken back from other field managers. The replicas are
+// left to an autoscaler whilst autoscaling is enabled, and reclaimed after.
+func (d *Deployment) ReclaimableFields() []string {
+	if d.Replicas == nil {
+		return nil
+	}
+	return []string{".spec.replicas"}
+}
+
+// Carries the found replicas into the apply whilst autoscaling, until another
+// field manager such as the autoscaler owns them. Leaving them out of the
+// apply beforehand would remove the field, which the API server then defaults
+// to a single replica.
+func (d *Deployment) Preserve(desired client.Object, found client.Object, fieldManager string) {
+	desiredDeployment, ok := desired.(*appsv1.Deployment)
+	if !ok || d.Replicas != nil {
+		return
+	}
+	foundDeployment, ok := found.(*appsv1.Deployment)
+	if !ok || foundDeployment.Spec.Replicas == nil || ownedByOthers(found, fieldManager, "spec", "replicas") {
+		return
+	}
+	desiredDeployment.Spec.Replicas = ptr.To(*foundDeployment.Spec.Replicas)
+}
+
+// Returns the replicas the Deployment is rendered with. Whilst autoscaling is
+// enabled none are rendered, so the HorizontalPodAutoscaler is not fought over
+// the field once it owns it. Until then Preserve carries the found replicas.
+func desiredReplicas(crd *cachev1alpha1.Samtest) *int32 {
+	if crd.Spec.Autoscaling != nil {
+		return nil
+	}
+	return ptr.To(crd.Spec.Replicas)
+}
+
+// Returns the update strategy of the Deployment. A claim which only a single
+// pod can mount must be released by the old pod before the new one starts, so
+// the pods are recreated. Otherwise the pods are rolled, with the default
+// surge and unavailability rendered explicitly so the operator owns them and
+// can drop them when switching to recreating the pods.
+func deploymentStrategy(crd *cachev1alpha1.Samtest) appsv1.DeploymentStrategy {
+	if singleMountPersistence(crd) {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       ptr.To(intstr.FromString("25%")),
+			MaxUnavailable: ptr.To(intstr.FromString("25%")),
+		},
+	}
+}
+
+// Builds the topology spread constraints of the pods. With SpreadReplicas set,
+// the pods are spread across zones and nodes, unless a constraint on the
+// Samtest already covers the same topology key.
+func (d *Deployment) topologySpreadConstraints() []corev1.TopologySpreadConstraint {
+	constraints := slices.Clone(d.TopologySpreadConstraints)
+	if !d.SpreadReplicas {
+		return constraints
+	}
+
+	for _, topologyKey := range []string{corev1.LabelTopologyZone, corev1.LabelHostname} {
+		if slices.ContainsFunc(constraints, func(constraint corev1.TopologySpreadConstraint) bool {
+			return constraint.TopologyKey == topologyKey
+		}) {
+			continue
+		}
+		constraints = append(constraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: d.Labels,
+			},
+		})
+	}
+	return constraints
+}
+
+// Builds the annotations of the pod template.
+func (d *Deployment) podAnnotations() map[string]string {
+	if d.ConfigChecksum == "" {
+		return nil
+	}
+	return map[string]string{ConfigChecksumAnnotation: d.ConfigChecksum}
+}
+
+// Returns the readiness probe of the main container. Without one set on the
+// Samtest, pods serving a port named http are only ready once it answers.
+func (d *Deployment) readinessProbe() *corev1.Probe {
+	if d.ReadinessProbe != nil {
+		return d.ReadinessProbe.DeepCopy()
+	}
+
+	for _, port := range d.Ports {
+		if port.Name != defaultProbePortName {
+			continue
+		}
+		return &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: "/",
+					Port: intstr.FromString(defaultProbePortName),
+				},
+			},
+			PeriodSeconds:    10,
+			TimeoutSeconds:   1,
+			SuccessThreshold: 1,
+			FailureThreshold: 3,
+		}
+	}
+	return nil
+}
+
+// Builds the environment of the main container, with the variables injected
+// by the operator ahead of those set on the Samtest.
+func (d *Deployment) containerEnv() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  reservedEnvPrefix + "NAME",
+			Value: d.Name,
+		},
+		{
+			Name: reservedEnvPrefix + "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.name"},
+			},
+		},
+		{
+			Name: reservedEnvPrefix + "POD_NAMESPACE",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.namespace"},
+			},
+		},
+	}
+
+	for _, envVar := range d.Env {
+		env = append(env, *envVar.DeepCopy())
+	}
+	return env
+}
+
+// Copies the envFrom sources of the main container, so the rendered object
+// never aliases the Samtest spec.
+func (d *Deployment) containerEnvFrom() []corev1.EnvFromSource {
+	if len(d.EnvFrom) == 0 {
+		return nil
+	}
+
+	envFrom := make([]corev1.EnvFromSource, 0, len(d.EnvFrom))
+	for _, source := range d.EnvFrom {
+		envFrom = append(envFrom, *source.DeepCopy())
+	}
+	return envFrom
+}
+
+// Merges explicitly set compute resources over those of a resource profile,
+// request by request and limit by limit. A profile limit below an explicit
+// request is raised to match it, as the pods would otherwise be rejected.
+func mergeResources(profile corev1.ResourceRequirements, explicit *corev1.ResourceRequirements) corev1.ResourceRequirements {
+	merged := *profile.DeepCopy()
+	if explicit == nil {
+		return merged
+	}
+
+	for name, quantity := range explicit.Requests {
+		if merged.Requests == nil {
+			merged.Requests = corev1.ResourceList{}
+		}
+		merged.Requests[name] = quantity.DeepCopy()
+	}
+	for name, quantity := range explicit.Limits {
+		if merged.Limits == nil {
+			merged.Limits = corev1.ResourceList{}
+		}
+		merged.Limits[name] = quantity.DeepCopy()
+	}
+	merged.Claims = append(merged.Claims, explicit.Claims...)
+
+	for name, request := range explicit.Requests {
+		if _, explicitLimit := explicit.Limits[name]; explicitLimit {
+			continue
+		}
+		if limit, ok := merged.Limits[name]; ok && request.Cmp(limit) > 0 {
+			merged.Limits[name] = request.DeepCopy()
+		}
+	}
+
+	return merged
+}
+
+// Determines the quality of service class of a pod running only the main
+// container with the provided compute resources. This is the intended class,
+// which a pod running injected sidecars may not be given.
+func qosClass(requirements corev1.ResourceRequirements) corev1.PodQOSClass {
+	computeResources := []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+	guaranteed := true
+	bestEffort := true
+	for _, name := range computeResources {
+		request, hasRequest := requirements.Requests[name]
+		limit, hasLimit := requirements.Limits[name]
+		if hasRequest || hasLimit {
+			bestEffort = false
+		}
+		// Requests default to the limits when unset
+		if !hasLimit || (hasRequest && request.Cmp(limit) != 0) {
+			guaranteed = false
+		}
+	}
+
+	switch {
+	case bestEffort:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	default:
+		return corev1.PodQOSBurstable
+	}
 }
@@ -0,0 +1,133 @@
+package resources
+
+import (
+	"context"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+type HorizontalPodAutoscaler struct {
+	Name        string
+	Namespace   string
+	Labels      k8s.Labels
+	MinReplicas *int32
+	MaxReplicas int32
+	Metrics     []autoscalingv2.MetricSpec
+}
+
+// New creates a new HorizontalPodAutoscaler scaling the Deployment of the
+// Samtest.
+func (h *HorizontalPodAutoscaler) New(crd *cachev1alpha1.Samtest) Resource {
+	hpa := &HorizontalPodAutoscaler{
+		Name:      crd.Name,
+		Namespace: crd.Namespace,
+		Labels:    k8s.CreateLabels(crd.Name),
+	}
+
+	spec := crd.Spec.Autoscaling
+	if spec == nil {
+		return hpa
+	}
+
+	if spec.MinReplicas != nil {
+		hpa.MinReplicas = ptr.To(*spec.MinReplicas)
+	}
+	hpa.MaxReplicas = spec.MaxReplicas
+	if spec.TargetCPUUtilizationPercentage != nil {
+		hpa.Metrics = append(hpa.Metrics, utilizationMetric(corev1.ResourceCPU, *spec.TargetCPUUtilizationPercentage))
+	}
+	if spec.TargetMemoryUtilizationPercentage != nil {
+		hpa.Metrics = append(hpa.Metrics, utilizationMetric(corev1.ResourceMemory, *spec.TargetMemoryUtilizationPercentage))
+	}
+	for _, metric := range spec.Metrics {
+		hpa.Metrics = append(hpa.Metrics, *metric.DeepCopy())
+	}
+
+	return hpa
+}
+
+// Returns the resource kind.
+func (h *HorizontalPodAutoscaler) Kind() string {
+	return "HorizontalPodAutoscaler"
+}
+
+// Creates a new HorizontalPodAutoscaler Kubernetes object.
+func (h *HorizontalPodAutoscaler) Generate() client.Object {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "autoscaling/v2",
+			Kind:       h.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      h.Name,
+			Namespace: h.Namespace,
+			Labels:    h.Labels,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       h.Name,
+			},
+			MinReplicas: h.MinReplicas,
+			MaxReplicas: h.MaxReplicas,
+			Metrics:     h.Metrics,
+		},
+	}
+}
+
+// Checks whether the found HorizontalPodAutoscaler matches the desired spec.
+// Fields left unset in the desired spec are ignored, so values defaulted by the
+// API server do not register as drift.
+func (h *HorizontalPodAutoscaler) IsEqual(found client.Object) bool {
+	foundHPA, ok := found.(*autoscalingv2.HorizontalPodAutoscaler)
+	if !ok {
+		return false
+	}
+
+	return equality.Semantic.DeepDerivative(h.Generate().(*autoscalingv2.HorizontalPodAutoscaler).Spec, foundHPA.Spec)
+}
+
+// A HorizontalPodAutoscaler is healthy once it exists. An autoscaler unable to
+// fetch metrics leaves the Deployment at its current scale, so the Samtest is
+// not held back waiting on the metrics pipeline.
+func (h *HorizontalPodAutoscaler) IsHealthy(_ context.Context, _ client.Reader, _ client.Object) (bool, string, error) {
+	return true, "", nil
+}
+
+// Reports the replicas seen and calculated by the found HorizontalPodAutoscaler
+// on the Samtest status.
+func (h *HorizontalPodAutoscaler) ReportStatus(found client.Object, status *cachev1alpha1.SamtestStatus) {
+	foundHPA, ok := found.(*autoscalingv2.HorizontalPodAutoscaler)
+	if !ok {
+		return
+	}
+
+	status.Autoscaling = &cachev1alpha1.AutoscalingStatus{
+		CurrentReplicas: foundHPA.Status.CurrentReplicas,
+		DesiredReplicas: foundHPA.Status.DesiredReplicas,
+	}
+}
+
+// Builds a metric targeting the average utilisation of a resource across the
+// pods, relative to their requests.
+func utilizationMetric(name corev1.ResourceName, percentage int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: ptr.To(percentage),
+			},
+		},
+	}
+}
@@ -0,0 +1,264 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+// HTTPRouteGroupVersionKind identifies the Gateway API HTTPRoute. The Gateway
+// API types are not compiled in, as the CRDs may not be installed, so routes
+// are handled as unstructured objects.
+var HTTPRouteGroupVersionKind = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Version: "v1",
+	Kind:    "HTTPRoute",
+}
+
+type HTTPRoute struct {
+	Name        string
+	Namespace   string
+	Labels      k8s.Labels
+	ParentRefs  []cachev1alpha1.RouteParentRef
+	Hostnames   []string
+	Matches     []cachev1alpha1.RoutePathMatch
+	BackendRefs []cachev1alpha1.RouteBackendRef
+	ServiceName string
+	ServicePort int32
+}
+
+// New creates a new HTTPRoute routing to the Service of the Samtest.
+func (h *HTTPRoute) New(crd *cachev1alpha1.Samtest) Resource {
+	route := &HTTPRoute{
+		Name:        crd.Name,
+		Namespace:   crd.Namespace,
+		Labels:      k8s.CreateLabels(crd.Name),
+		ServiceName: crd.Name,
+	}
+
+	spec := crd.Spec.Route
+	if spec == nil {
+		return route
+	}
+
+	route.ParentRefs = slices.Clone(spec.ParentRefs)
+	route.Hostnames = slices.Clone(spec.Hostnames)
+	route.Matches = slices.Clone(spec.Matches)
+	route.BackendRefs = slices.Clone(spec.BackendRefs)
+
+	// Gateway API backends reference a Service port by number
+	portName := spec.Port
+	if portName == "" {
+		portName = defaultPort.Name
+	}
+	for _, port := range servicePorts(samtestPorts(crd)) {
+		if port.Name == portName {
+			route.ServicePort = port.Port
+		}
+	}
+
+	return route
+}
+
+// Returns the resource kind.
+func (h *HTTPRoute) Kind() string {
+	return HTTPRouteGroupVersionKind.Kind
+}
+
+// Creates a new HTTPRoute Kubernetes object with a single rule, matching the
+// configured paths and splitting requests across the backends by weight.
+func (h *HTTPRoute) Generate() client.Object {
+	parentRefs := make([]interface{}, 0, len(h.ParentRefs))
+	for _, parentRef := range h.ParentRefs {
+		ref := map[string]interface{}{
+			"group": HTTPRouteGroupVersionKind.Group,
+			"kind":  "Gateway",
+			"name":  parentRef.Name,
+		}
+		if parentRef.Namespace != "" {
+			ref["namespace"] = parentRef.Namespace
+		}
+		if parentRef.SectionName != "" {
+			ref["sectionName"] = parentRef.SectionName
+		}
+		parentRefs = append(parentRefs, ref)
+	}
+
+	rule := map[string]interface{}{
+		"backendRefs": h.backendRefs(),
+	}
+	if len(h.Matches) > 0 {
+		matches := make([]interface{}, 0, len(h.Matches))
+		for _, match := range h.Matches {
+			matchType := match.Type
+			if matchType == "" {
+				matchType = "PathPrefix"
+			}
+			matchValue := match.Value
+			if matchValue == "" {
+				matchValue = "/"
+			}
+			matches = append(matches, map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":  matchType,
+					"value": matchValue,
+				},
+			})
+		}
+		rule["matches"] = matches
+	}
+
+	spec := map[string]interface{}{
+		"parentRefs": parentRefs,
+		"rules":      []interface{}{rule},
+	}
+	if len(h.Hostnames) > 0 {
+		hostnames := make([]interface{}, 0, len(h.Hostnames))
+		for _, hostname := range h.Hostnames {
+			hostnames = append(hostnames, hostname)
+		}
+		spec["hostnames"] = hostnames
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": spec,
+	}}
+	route.SetGroupVersionKind(HTTPRouteGroupVersionKind)
+	route.SetName(h.Name)
+	route.SetNamespace(h.Namespace)
+	route.SetLabels(h.Labels)
+
+	return route
+}
+
+// Renders the backends of the route rule. Backends without a name are the
+// Service of the Samtest, reached on the port named by the route.
+func (h *HTTPRoute) backendRefs() []interface{} {
+	backendRefs := h.BackendRefs
+	if len(backendRefs) == 0 {
+		backendRefs = []cachev1alpha1.RouteBackendRef{{Weight: 1}}
+	}
+
+	refs := make([]interface{}, 0, len(backendRefs))
+	for _, backendRef := range backendRefs {
+		name, port := backendRef.Name, backendRef.Port
+		if name == "" {
+			name, port = h.ServiceName, h.ServicePort
+		}
+		refs = append(refs, map[string]interface{}{
+			"group":  "",
+			"kind":   "Service",
+			"name":   name,
+			"port":   int64(port),
+			"weight": int64(backendRef.Weight),
+		})
+	}
+	return refs
+}
+
+// Checks whether the found HTTPRoute matches the desired spec. Fields left
+// unset in the desired spec are ignored, so values defaulted by the API server
+// do not register as drift.
+func (h *HTTPRoute) IsEqual(found client.Object) bool {
+	foundRoute, ok := found.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+
+	desired := h.Generate().(*unstructured.Unstructured)
+	return equality.Semantic.DeepDerivative(desired.Object["spec"], foundRoute.Object["spec"])
+}
+
+// Checks whether every parent Gateway has accepted the HTTPRoute.
+func (h *HTTPRoute) IsHealthy(_ context.Context, _ client.Reader, found client.Object) (bool, string, error) {
+	parents, err := routeParents(found)
+	if err != nil {
+		return false, "", err
+	}
+
+	// A parent without a namespace is in the namespace of the HTTPRoute, both
+	// in the spec and in the status reported by the Gateway controller
+	inRouteNamespace := func(namespace string) string {
+		if namespace == "" {
+			return h.Namespace
+		}
+		return namespace
+	}
+
+	for _, parentRef := range h.ParentRefs {
+		accepted := false
+		for _, parent := range parents {
+			if parent.Name == parentRef.Name && inRouteNamespace(parent.Namespace) == inRouteNamespace(parentRef.Namespace) {
+				accepted = accepted || meta.IsStatusConditionTrue(parent.Conditions, "Accepted")
+			}
+		}
+		if !accepted {
+			return false, fmt.Sprintf("HTTPRoute %s has not been accepted by Gateway %s", h.Name, parentRef.Name), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// Reflects the conditions reported by each parent Gateway of the found
+// HTTPRoute on the Samtest status.
+func (h *HTTPRoute) ReportStatus(found client.Object, status *cachev1alpha1.SamtestStatus) {
+	parents, err := routeParents(found)
+	if err != nil {
+		return
+	}
+	status.RouteParents = parents
+}
+
+// The status of a single parent of an HTTPRoute, as reported by the Gateway
+// controller.
+type routeParentStatus struct {
+	ParentRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace,omitempty"`
+	} `json:"parentRef"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Decodes the parent statuses of a found HTTPRoute.
+func routeParents(found client.Object) ([]cachev1alpha1.RouteParentStatus, error) {
+	foundRoute, ok := found.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("expected an unstructured HTTPRoute, got %T", found)
+	}
+
+	items, _, err := unstructured.NestedSlice(foundRoute.Object, "status", "parents")
+	if err != nil {
+		return nil, err
+	}
+
+	var parents []cachev1alpha1.RouteParentStatus
+	for _, item := range items {
+		content, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		parent := routeParentStatus{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, &parent); err != nil {
+			return nil, err
+		}
+		parents = append(parents, cachev1alpha1.RouteParentStatus{
+			Name:       parent.ParentRef.Name,
+			Namespace:  parent.ParentRef.Namespace,
+			Conditions: parent.Conditions,
+		})
+	}
+	return parents, nil
+}
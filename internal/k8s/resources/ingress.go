@@ -0,0 +1,170 @@
+package resources
+
+import (
+	"context"
+	"maps"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+type Ingress struct {
+	Name             string
+	Namespace        string
+	Labels           k8s.Labels
+	Annotations      map[string]string
+	IngressClassName *string
+	Hosts            []string
+	Paths            []cachev1alpha1.IngressPath
+	ServiceName      string
+	Port             string
+	TLSSecretName    string
+}
+
+// New creates a new Ingress routing to the Service of the Samtest.
+func (i *Ingress) New(crd *cachev1alpha1.Samtest) Resource {
+	ingress := &Ingress{
+		Name:        crd.Name,
+		Namespace:   crd.Namespace,
+		Labels:      k8s.CreateLabels(crd.Name),
+		ServiceName: crd.Name,
+		Port:        defaultPort.Name,
+	}
+
+	if spec := crd.Spec.Ingress; spec != nil {
+		ingress.Annotations = maps.Clone(spec.Annotations)
+		if spec.IngressClassName != nil {
+			ingress.IngressClassName = ptr.To(*spec.IngressClassName)
+		}
+		ingress.Hosts = append(ingress.Hosts, spec.Hosts...)
+		ingress.Paths = append(ingress.Paths, spec.Paths...)
+		if spec.Port != "" {
+			ingress.Port = spec.Port
+		}
+		ingress.TLSSecretName = spec.TLSSecretName
+	}
+
+	return ingress
+}
+
+// Returns the resource kind.
+func (i *Ingress) Kind() string {
+	return "Ingress"
+}
+
+// Creates a new Ingress Kubernetes object, routing every path on every host to
+// the named port of the Service.
+func (i *Ingress) Generate() client.Object {
+	paths := i.Paths
+	if len(paths) == 0 {
+		paths = []cachev1alpha1.IngressPath{{Path: "/", PathType: networkingv1.PathTypePrefix}}
+	}
+
+	httpPaths := make([]networkingv1.HTTPIngressPath, 0, len(paths))
+	for _, path := range paths {
+		pathType := path.PathType
+		if pathType == "" {
+			pathType = networkingv1.PathTypePrefix
+		}
+		httpPaths = append(httpPaths, networkingv1.HTTPIngressPath{
+			Path:     path.Path,
+			PathType: ptr.To(pathType),
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: i.ServiceName,
+					Port: networkingv1.ServiceBackendPort{Name: i.Port},
+				},
+			},
+		})
+	}
+
+	// Without any hosts a single rule matches requests for every host
+	hosts := i.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+
+	rules := make([]networkingv1.IngressRule, 0, len(hosts))
+	for _, host := range hosts {
+		rules = append(rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: httpPaths,
+				},
+			},
+		})
+	}
+
+	ingress := &networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       i.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        i.Name,
+			Namespace:   i.Namespace,
+			Labels:      i.Labels,
+			Annotations: i.Annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: i.IngressClassName,
+			Rules:            rules,
+		},
+	}
+
+	if i.TLSSecretName != "" {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{
+			{
+				Hosts:      i.Hosts,
+				SecretName: i.TLSSecretName,
+			},
+		}
+	}
+
+	return ingress
+}
+
+// Checks whether the found Ingress matches the desired spec. Fields left unset
+// in the desired spec are ignored, so values defaulted by the API server do
+// not register as drift.
+func (i *Ingress) IsEqual(found client.Object) bool {
+	foundIngress, ok := found.(*networkingv1.Ingress)
+	if !ok {
+		return false
+	}
+
+	desired := i.Generate().(*networkingv1.Ingress)
+	return hasAnnotations(desired.Annotations, foundIngress) &&
+		equality.Semantic.DeepDerivative(desired.Spec, foundIngress.Spec)
+}
+
+// An Ingress is healthy once it exists. Not every ingress controller reports
+// an address, so the Samtest is not held back waiting for one.
+func (i *Ingress) IsHealthy(_ context.Context, _ client.Reader, _ client.Object) (bool, string, error) {
+	return true, "", nil
+}
+
+// Reports the addresses assigned to the found Ingress on the Samtest status.
+func (i *Ingress) ReportStatus(found client.Object, status *cachev1alpha1.SamtestStatus) {
+	foundIngress, ok := found.(*networkingv1.Ingress)
+	if !ok {
+		return
+	}
+
+	status.IngressAddresses = nil
+	for _, ingress := range foundIngress.Status.LoadBalancer.Ingress {
+		switch {
+		case ingress.IP != "":
+			status.IngressAddresses = append(status.IngressAddresses, ingress.IP)
+		case ingress.Hostname != "":
+			status.IngressAddresses = append(status.IngressAddresses, ingress.Hostname)
+		}
+	}
+}
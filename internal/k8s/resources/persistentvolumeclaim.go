@@ -0,0 +1,142 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+// persistenceVolumeName is the name of the pod volume backed by the
+// PersistentVolumeClaim of the Samtest.
+const persistenceVolumeName = "data"
+
+// Reports whether the claim of the Samtest can only be mounted by a single
+// pod, so the pods are replaced rather than rolled.
+func singleMountPersistence(crd *cachev1alpha1.Samtest) bool {
+	if crd.Spec.Persistence == nil {
+		return false
+	}
+	return slices.ContainsFunc(crd.Spec.Persistence.AccessModes, func(mode corev1.PersistentVolumeAccessMode) bool {
+		return mode == corev1.ReadWriteOnce || mode == corev1.ReadWriteOncePod
+	})
+}
+
+type PersistentVolumeClaim struct {
+	Name             string
+	Namespace        string
+	Labels           k8s.Labels
+	Size             resource.Quantity
+	StorageClassName *string
+	AccessModes      []corev1.PersistentVolumeAccessMode
+	RetainOnDelete   bool
+}
+
+// New creates a new PersistentVolumeClaim for the persistence of the Samtest.
+func (p *PersistentVolumeClaim) New(crd *cachev1alpha1.Samtest) Resource {
+	pvc := &PersistentVolumeClaim{
+		Name:      crd.Name,
+		Namespace: crd.Namespace,
+		Labels:    k8s.CreateLabels(crd.Name),
+	}
+
+	if spec := crd.Spec.Persistence; spec != nil {
+		pvc.Size = spec.Size.DeepCopy()
+		pvc.StorageClassName = spec.StorageClassName
+		pvc.AccessModes = slices.Clone(spec.AccessModes)
+		pvc.RetainOnDelete = spec.RetainOnDelete
+	}
+
+	return pvc
+}
+
+// Returns the resource kind.
+func (p *PersistentVolumeClaim) Kind() string {
+	return "PersistentVolumeClaim"
+}
+
+// Creates a new PersistentVolumeClaim Kubernetes object.
+func (p *PersistentVolumeClaim) Generate() client.Object {
+	return &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       p.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.Name,
+			Namespace: p.Namespace,
+			Labels:    p.Labels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      p.AccessModes,
+			StorageClassName: p.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: p.Size,
+				},
+			},
+		},
+	}
+}
+
+// Checks whether the found PersistentVolumeClaim requests at least the desired
+// size. The size of a claim can only be expanded, so a claim larger than
+// desired is in sync. Every other field of a claim is immutable.
+func (p *PersistentVolumeClaim) IsEqual(found client.Object) bool {
+	foundPVC, ok := found.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return false
+	}
+
+	foundSize := foundPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+	return foundSize.Cmp(p.Size) >= 0
+}
+
+// Carries the found size into the apply when it is larger than desired, such
+// as for a claim expanded by hand, as a claim cannot be shrunk.
+func (p *PersistentVolumeClaim) Preserve(desired client.Object, found client.Object, _ string) {
+	desiredPVC, ok := desired.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+	foundPVC, ok := found.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+
+	foundSize := foundPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+	if foundSize.Cmp(p.Size) > 0 {
+		desiredPVC.Spec.Resources.Requests[corev1.ResourceStorage] = foundSize.DeepCopy()
+	}
+}
+
+// Claims hold data, so they are released on deletion when asked to, on top of
+// the Retain deletion policy.
+func (p *PersistentVolumeClaim) Retained(policy cachev1alpha1.DeletionPolicy) bool {
+	return p.RetainOnDelete || policy == cachev1alpha1.DeletionPolicyRetain
+}
+
+// Checks whether the found PersistentVolumeClaim is bound to a volume. Claims
+// of a storage class which waits for a consumer only bind once a pod using
+// them is scheduled, which happens alongside.
+func (p *PersistentVolumeClaim) IsHealthy(_ context.Context, _ client.Reader, found client.Object) (bool, string, error) {
+	foundPVC, ok := found.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return false, "", fmt.Errorf("expected a PersistentVolumeClaim, got %T", found)
+	}
+
+	if foundPVC.Status.Phase != corev1.ClaimBound {
+		return false, fmt.Sprintf("PersistentVolumeClaim %s is not bound", p.Name), nil
+	}
+	return true, "", nil
+}
+
+// The PersistentVolumeClaim reports nothing on the Samtest status.
+func (p *PersistentVolumeClaim) ReportStatus(_ client.Object, _ *cachev1alpha1.SamtestStatus) {}
@@ -0,0 +1,97 @@
+package resources
+
+import (
+	"context"
+
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+type PodDisruptionBudget struct {
+	Name           string
+	Namespace      string
+	Labels         k8s.Labels
+	MinAvailable   *intstr.IntOrString
+	MaxUnavailable *intstr.IntOrString
+}
+
+// New creates a new PodDisruptionBudget selecting the pods of the Samtest.
+func (p *PodDisruptionBudget) New(crd *cachev1alpha1.Samtest) Resource {
+	pdb := &PodDisruptionBudget{
+		Name:      crd.Name,
+		Namespace: crd.Namespace,
+		Labels:    k8s.CreateLabels(crd.Name),
+	}
+
+	if spec := crd.Spec.DisruptionBudget; spec != nil {
+		if spec.MinAvailable != nil {
+			minAvailable := *spec.MinAvailable
+			pdb.MinAvailable = &minAvailable
+		}
+		if spec.MaxUnavailable != nil {
+			maxUnavailable := *spec.MaxUnavailable
+			pdb.MaxUnavailable = &maxUnavailable
+		}
+	}
+
+	return pdb
+}
+
+// Returns the resource kind.
+func (p *PodDisruptionBudget) Kind() string {
+	return "PodDisruptionBudget"
+}
+
+// Creates a new PodDisruptionBudget Kubernetes object.
+func (p *PodDisruptionBudget) Generate() client.Object {
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1",
+			Kind:       p.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.Name,
+			Namespace: p.Namespace,
+			Labels:    p.Labels,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: p.Labels,
+			},
+			MinAvailable:   p.MinAvailable,
+			MaxUnavailable: p.MaxUnavailable,
+		},
+	}
+}
+
+// Checks whether the found PodDisruptionBudget matches the desired spec.
+// Fields left unset in the desired spec are ignored, so values defaulted by
+// the API server do not register as drift.
+func (p *PodDisruptionBudget) IsEqual(found client.Object) bool {
+	foundPDB, ok := found.(*policyv1.PodDisruptionBudget)
+	if !ok {
+		return false
+	}
+
+	desired := p.Generate().(*policyv1.PodDisruptionBudget)
+	// Switching between minAvailable and maxUnavailable leaves the other unset
+	// in the desired spec, so it is compared explicitly
+	return equality.Semantic.DeepDerivative(desired.Spec, foundPDB.Spec) &&
+		(desired.Spec.MinAvailable == nil) == (foundPDB.Spec.MinAvailable == nil) &&
+		(desired.Spec.MaxUnavailable == nil) == (foundPDB.Spec.MaxUnavailable == nil)
+}
+
+// A PodDisruptionBudget is healthy once it exists. Whether disruptions are
+// currently allowed depends on the pods, which the Deployment reports on.
+func (p *PodDisruptionBudget) IsHealthy(_ context.Context, _ client.Reader, _ client.Object) (bool, string, error) {
+	return true, "", nil
+}
+
+// The PodDisruptionBudget reports nothing on the Samtest status.
+func (p *PodDisruptionBudget) ReportStatus(_ client.Object, _ *cachev1alpha1.SamtestStatus) {}
@@ -0,0 +1,81 @@
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+)
+
+// The port exposed when a Samtest does not list any.
+var defaultPort = cachev1alpha1.Port{
+	Name:          "http",
+	ContainerPort: 80,
+	Protocol:      corev1.ProtocolTCP,
+}
+
+// The name of the port probed for readiness when the Samtest sets no readiness
+// probe of its own.
+const defaultProbePortName = "http"
+
+// Returns a copy of the ports of the Samtest, falling back to the default
+// port when none are listed.
+func samtestPorts(crd *cachev1alpha1.Samtest) []cachev1alpha1.Port {
+	if len(crd.Spec.Ports) == 0 {
+		return []cachev1alpha1.Port{defaultPort}
+	}
+
+	ports := make([]cachev1alpha1.Port, 0, len(crd.Spec.Ports))
+	for _, port := range crd.Spec.Ports {
+		ports = append(ports, *port.DeepCopy())
+	}
+	return ports
+}
+
+// Returns the protocol of the port, which the CRD defaults to TCP.
+func portProtocol(port cachev1alpha1.Port) corev1.Protocol {
+	if port.Protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return port.Protocol
+}
+
+// Renders the ports of the main container.
+func containerPorts(ports []cachev1alpha1.Port) []corev1.ContainerPort {
+	containerPorts := make([]corev1.ContainerPort, 0, len(ports))
+	for _, port := range ports {
+		containerPorts = append(containerPorts, corev1.ContainerPort{
+			Name:          port.Name,
+			ContainerPort: port.ContainerPort,
+			Protocol:      portProtocol(port),
+		})
+	}
+	return containerPorts
+}
+
+// Renders the ports of the Service, each targeting the container port of the
+// same name.
+func servicePorts(ports []cachev1alpha1.Port) []corev1.ServicePort {
+	servicePorts := make([]corev1.ServicePort, 0, len(ports))
+	for _, port := range ports {
+		servicePort := port.ServicePort
+		if servicePort == 0 {
+			servicePort = port.ContainerPort
+		}
+
+		var appProtocol *string
+		if port.AppProtocol != nil {
+			appProtocol = ptr.To(*port.AppProtocol)
+		}
+
+		servicePorts = append(servicePorts, corev1.ServicePort{
+			Name:        port.Name,
+			Protocol:    portProtocol(port),
+			AppProtocol: appProtocol,
+			Port:        servicePort,
+			TargetPort:  intstr.FromString(port.Name),
+		})
+	}
+	return servicePorts
+}
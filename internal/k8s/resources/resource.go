@@ -1,14 +1,126 @@
 package resources
 
 import (
+	"context"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
 )
 
+// Resource renders a Kubernetes resource managed for a Samtest. Every resource
+// is applied on each pass, so fields removed from the Samtest are removed from
+// the cluster. IsEqual only decides whether drift is reported.
 type Resource interface {
 	New(crd *cachev1alpha1.Samtest) Resource
 	Kind() string
 	Generate() client.Object
 	IsEqual(client.Object) bool
+	IsHealthy(ctx context.Context, reader client.Reader, found client.Object) (bool, string, error)
+	ReportStatus(found client.Object, status *cachev1alpha1.SamtestStatus)
+}
+
+// Retainable is implemented by resources which hold state that can outlive
+// the Samtest. Retained resources are released rather than deleted when the
+// Samtest is torn down.
+type Retainable interface {
+	Retained(policy cachev1alpha1.DeletionPolicy) bool
+}
+
+// Replaceable is implemented by resources with immutable fields. A found
+// resource which cannot be updated to the desired spec in place is deleted so
+// it can be recreated.
+type Replaceable interface {
+	RequiresReplacement(found client.Object) bool
+}
+
+// Reclaimable is implemented by resources with fields which other controllers
+// take over for a time, such as the replicas of an autoscaled Deployment. An
+// apply which only conflicts on these fields is forced to take them back.
+type Reclaimable interface {
+	ReclaimableFields() []string
+}
+
+// Preserving is implemented by resources with fields whose desired value
+// depends on the found resource. The found values are carried into the desired
+// object before it is applied by the field manager.
+type Preserving interface {
+	Preserve(desired client.Object, found client.Object, fieldManager string)
+}
+
+// Reports whether a field of the found object is owned by any field manager
+// other than the one given. The path names the field, such as spec, replicas.
+func ownedByOthers(found client.Object, fieldManager string, path ...string) bool {
+	for _, entry := range found.GetManagedFields() {
+		if entry.Manager == fieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		owned := true
+		for _, name := range path {
+			next, ok := fields["f:"+name].(map[string]interface{})
+			if !ok {
+				owned = false
+				break
+			}
+			fields = next
+		}
+		if owned {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks whether every desired annotation is set on the found object. Other
+// annotations on the found object are ignored.
+func hasAnnotations(desired map[string]string, found client.Object) bool {
+	foundAnnotations := found.GetAnnotations()
+	for key, value := range desired {
+		if foundValue, ok := foundAnnotations[key]; !ok || foundValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// NewApplyConfiguration converts a generated object into an apply configuration
+// for server-side apply. Only the fields set on the object are kept, so the
+// field manager takes ownership of exactly what was rendered.
+func NewApplyConfiguration(obj client.Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(content, "status")
+	pruneNulls(content)
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// Removes null values, such as unset timestamps, which server-side apply
+// would otherwise treat as a request to clear the field.
+func pruneNulls(content map[string]interface{}) {
+	for key, value := range content {
+		switch v := value.(type) {
+		case nil:
+			delete(content, key)
+		case map[string]interface{}:
+			pruneNulls(v)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					pruneNulls(m)
+				}
+			}
+		}
+	}
 }
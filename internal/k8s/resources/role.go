@@ -0,0 +1,75 @@
+package resources
+
+import (
+	"context"
+	"slices"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+type Role struct {
+	Name      string
+	Namespace string
+	Labels    k8s.Labels
+	Rules     []rbacv1.PolicyRule
+}
+
+// New creates a new Role granting the RBAC rules of the Samtest.
+func (r *Role) New(crd *cachev1alpha1.Samtest) Resource {
+	role := &Role{
+		Name:      crd.Name,
+		Namespace: crd.Namespace,
+		Labels:    k8s.CreateLabels(crd.Name),
+	}
+
+	if crd.Spec.RBAC != nil {
+		role.Rules = slices.Clone(crd.Spec.RBAC.Rules)
+	}
+
+	return role
+}
+
+// Returns the resource kind.
+func (r *Role) Kind() string {
+	return "Role"
+}
+
+// Creates a new Role Kubernetes object.
+func (r *Role) Generate() client.Object {
+	return &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       r.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Labels:    r.Labels,
+		},
+		Rules: r.Rules,
+	}
+}
+
+// Checks whether the found Role grants exactly the desired rules.
+func (r *Role) IsEqual(found client.Object) bool {
+	foundRole, ok := found.(*rbacv1.Role)
+	if !ok {
+		return false
+	}
+
+	return equality.Semantic.DeepEqual(r.Rules, foundRole.Rules)
+}
+
+// A Role is healthy once it exists.
+func (r *Role) IsHealthy(_ context.Context, _ client.Reader, _ client.Object) (bool, string, error) {
+	return true, "", nil
+}
+
+// The Role reports nothing on the Samtest status.
+func (r *Role) ReportStatus(_ client.Object, _ *cachev1alpha1.SamtestStatus) {}
@@ -0,0 +1,86 @@
+package resources
+
+import (
+	"context"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+type RoleBinding struct {
+	Name               string
+	Namespace          string
+	Labels             k8s.Labels
+	RoleName           string
+	ServiceAccountName string
+}
+
+// New creates a new RoleBinding granting the Role of the Samtest to the
+// ServiceAccount its pods run as.
+func (r *RoleBinding) New(crd *cachev1alpha1.Samtest) Resource {
+	return &RoleBinding{
+		Name:               crd.Name,
+		Namespace:          crd.Namespace,
+		Labels:             k8s.CreateLabels(crd.Name),
+		RoleName:           crd.Name,
+		ServiceAccountName: serviceAccountName(crd),
+	}
+}
+
+// Returns the resource kind.
+func (r *RoleBinding) Kind() string {
+	return "RoleBinding"
+}
+
+// Creates a new RoleBinding Kubernetes object.
+func (r *RoleBinding) Generate() client.Object {
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       r.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Labels:    r.Labels,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     r.RoleName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      r.ServiceAccountName,
+				Namespace: r.Namespace,
+			},
+		},
+	}
+}
+
+// Checks whether the found RoleBinding binds the desired ServiceAccount. The
+// role reference of a RoleBinding is immutable, and always names the Role of
+// the Samtest.
+func (r *RoleBinding) IsEqual(found client.Object) bool {
+	foundBinding, ok := found.(*rbacv1.RoleBinding)
+	if !ok {
+		return false
+	}
+
+	desired := r.Generate().(*rbacv1.RoleBinding)
+	return equality.Semantic.DeepEqual(desired.Subjects, foundBinding.Subjects)
+}
+
+// A RoleBinding is healthy once it exists.
+func (r *RoleBinding) IsHealthy(_ context.Context, _ client.Reader, _ client.Object) (bool, string, error) {
+	return true, "", nil
+}
+
+// The RoleBinding reports nothing on the Samtest status.
+func (r *RoleBinding) ReportStatus(_ client.Object, _ *cachev1alpha1.SamtestStatus) {}
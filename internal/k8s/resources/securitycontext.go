@@ -0,0 +1,58 @@
+package resources
+
+import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+)
+
+// Builds the security context of the pods, overlaying the overrides of the
+// Samtest onto defaults which comply with the restricted Pod Security Standard.
+func podSecurityContext(crd *cachev1alpha1.Samtest) *corev1.PodSecurityContext {
+	securityContext := &corev1.PodSecurityContext{
+		RunAsNonRoot: ptr.To(true),
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+
+	if crd.Spec.SecurityContext != nil && crd.Spec.SecurityContext.Pod != nil {
+		overlay(securityContext, crd.Spec.SecurityContext.Pod.DeepCopy())
+	}
+	return securityContext
+}
+
+// Builds the security context of the main container, overlaying the overrides
+// of the Samtest onto defaults which comply with the restricted Pod Security
+// Standard.
+func containerSecurityContext(crd *cachev1alpha1.Samtest) *corev1.SecurityContext {
+	securityContext := &corev1.SecurityContext{
+		RunAsNonRoot:             ptr.To(true),
+		AllowPrivilegeEscalation: ptr.To(false),
+		ReadOnlyRootFilesystem:   ptr.To(true),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
+
+	if crd.Spec.SecurityContext != nil && crd.Spec.SecurityContext.Container != nil {
+		overlay(securityContext, crd.Spec.SecurityContext.Container.DeepCopy())
+	}
+	return securityContext
+}
+
+// Replaces every field of the defaults which is set on the override. Security
+// context fields are all optional pointers or slices, so a set field is one
+// which is not the zero value.
+func overlay[T any](defaults *T, override *T) {
+	dst := reflect.ValueOf(defaults).Elem()
+	src := reflect.ValueOf(override).Elem()
+	for i := range src.NumField() {
+		if !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
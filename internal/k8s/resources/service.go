@@ -1,10 +1,15 @@
 package resources
 
 import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
@@ -12,18 +17,47 @@ import (
 )
 
 type Service struct {
-	Name      string
-	Namespace string
-	Labels    k8s.Labels
+	Name            string
+	Namespace       string
+	Labels          k8s.Labels
+	Annotations     map[string]string
+	Ports           []cachev1alpha1.Port
+	Type            cachev1alpha1.ServiceType
+	ExpectEndpoints bool
+
+	ExternalTrafficPolicy    corev1.ServiceExternalTrafficPolicy
+	SessionAffinity          corev1.ServiceAffinity
+	LoadBalancerSourceRanges []string
+
+	// ClusterDomain is the DNS domain of the cluster configured on the
+	// operator, which the reported DNS name is qualified with.
+	ClusterDomain string
 }
 
 // New creates a new Service with default events.
 func (s *Service) New(crd *cachev1alpha1.Samtest) Resource {
-	return &Service{
+	service := &Service{
 		Name:      crd.Name,
 		Namespace: crd.Namespace,
 		Labels:    k8s.CreateLabels(crd.Name),
+		Ports:     samtestPorts(crd),
+		Type:      cachev1alpha1.ServiceTypeClusterIP,
+		// A Service for a workload scaled to zero has no endpoints to wait on
+		ExpectEndpoints: crd.Spec.Replicas > 0 || crd.Spec.Autoscaling != nil,
+		ClusterDomain:   s.ClusterDomain,
+	}
+
+	if spec := crd.Spec.Service; spec != nil {
+		if spec.Type != "" {
+			service.Type = spec.Type
+		}
+		service.Annotations = maps.Clone(spec.Annotations)
+		service.ExternalTrafficPolicy = spec.ExternalTrafficPolicy
+		service.SessionAffinity = spec.SessionAffinity
+		service.LoadBalancerSourceRanges = slices.Clone(spec.LoadBalancerSourceRanges)
 	}
+
+	return service
 }
 
 // Returns the resource kind.
@@ -31,38 +65,123 @@ func (s *Service) Kind() string {
 	return "Service"
 }
 
-// Creates a new Service Kubernetes object.
+// Creates a new Service Kubernetes object. The cluster IP and node ports are
+// never rendered, so those allocated by the API server are kept on update.
 func (s *Service) Generate() client.Object {
-	return &corev1.Service{
+	service := &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
 			Kind:       s.Kind(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      s.Name,
-			Namespace: s.Namespace,
-			Labels:    s.Labels,
+			Name:        s.Name,
+			Namespace:   s.Namespace,
+			Labels:      s.Labels,
+			Annotations: s.Annotations,
 		},
 		Spec: corev1.ServiceSpec{
-			Type:     corev1.ServiceTypeClusterIP,
-			Selector: s.Labels,
-			Ports: []corev1.ServicePort{
-				{
-					Name:       "http",
-					Protocol:   corev1.ProtocolTCP,
-					Port:       80,
-					TargetPort: intstr.FromString("http"),
-				},
-			},
+			Type:                     corev1.ServiceType(s.Type),
+			Selector:                 s.Labels,
+			Ports:                    servicePorts(s.Ports),
+			ExternalTrafficPolicy:    s.ExternalTrafficPolicy,
+			SessionAffinity:          s.SessionAffinity,
+			LoadBalancerSourceRanges: s.LoadBalancerSourceRanges,
 		},
 	}
+
+	if s.Type == cachev1alpha1.ServiceTypeHeadless {
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+		service.Spec.ClusterIP = corev1.ClusterIPNone
+	}
+
+	return service
+}
+
+// Checks whether the found Service matches the desired spec. Fields left unset
+// in the desired spec are ignored, so values defaulted or allocated by the API
+// server, such as the cluster IP and node ports, do not register as drift.
+func (s *Service) IsEqual(found client.Object) bool {
+	foundService, ok := found.(*corev1.Service)
+	if !ok {
+		return false
+	}
+
+	desired := s.Generate().(*corev1.Service)
+	return hasAnnotations(desired.Annotations, foundService) &&
+		equality.Semantic.DeepDerivative(desired.Spec, foundService.Spec)
 }
 
-func (d *Service) IsEqual(found client.Object) bool {
+// Reports whether the found Service has to be replaced, as the cluster IP
+// cannot be changed between headless and allocated in place.
+func (s *Service) RequiresReplacement(found client.Object) bool {
 	foundService, ok := found.(*corev1.Service)
 	if !ok {
 		return false
 	}
 
-	return equality.Semantic.DeepEqual(d.Generate().(*corev1.Service).Spec, foundService.Spec)
+	headless := foundService.Spec.ClusterIP == corev1.ClusterIPNone
+	return headless != (s.Type == cachev1alpha1.ServiceTypeHeadless)
+}
+
+// Reports whether the Service is kept when the Samtest is deleted, preserving
+// its allocated address under the Retain policy.
+func (s *Service) Retained(policy cachev1alpha1.DeletionPolicy) bool {
+	return policy == cachev1alpha1.DeletionPolicyRetain
+}
+
+// Checks whether the Service has at least one ready endpoint to route to, and
+// for a LoadBalancer Service whether the load balancer has an address.
+func (s *Service) IsHealthy(ctx context.Context, reader client.Reader, found client.Object) (bool, string, error) {
+	foundService, ok := found.(*corev1.Service)
+	if !ok {
+		return false, "", fmt.Errorf("expected a Service, got %T", found)
+	}
+
+	if s.Type == cachev1alpha1.ServiceTypeLoadBalancer && len(foundService.Status.LoadBalancer.Ingress) == 0 {
+		return false, fmt.Sprintf("Service %s is waiting for a load balancer address", s.Name), nil
+	}
+
+	if !s.ExpectEndpoints {
+		return true, "", nil
+	}
+
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := reader.List(ctx, endpointSlices,
+		client.InNamespace(s.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: s.Name},
+	); err != nil {
+		return false, "", err
+	}
+
+	for _, endpointSlice := range endpointSlices.Items {
+		for _, endpoint := range endpointSlice.Endpoints {
+			// A nil ready condition is documented as ready
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				return true, "", nil
+			}
+		}
+	}
+
+	return false, fmt.Sprintf("Service %s has no ready endpoints", s.Name), nil
+}
+
+// Reports the address of the found Service on the Samtest status.
+func (s *Service) ReportStatus(found client.Object, status *cachev1alpha1.SamtestStatus) {
+	foundService, ok := found.(*corev1.Service)
+	if !ok {
+		return
+	}
+
+	status.ClusterIP = foundService.Spec.ClusterIP
+	status.DNSName = fmt.Sprintf("%s.%s.svc.%s", s.Name, s.Namespace, s.ClusterDomain)
+
+	status.LoadBalancerAddresses = nil
+	for _, ingress := range foundService.Status.LoadBalancer.Ingress {
+		switch {
+		case ingress.IP != "":
+			status.LoadBalancerAddresses = append(status.LoadBalancerAddresses, ingress.IP)
+		case ingress.Hostname != "":
+			status.LoadBalancerAddresses = append(status.LoadBalancerAddresses, ingress.Hostname)
+		}
+	}
 }
@@ -0,0 +1,104 @@
+package resources
+
+import (
+	"context"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+	"github.com/s-humphreys/go-operator-sdk/internal/k8s"
+)
+
+type ServiceAccount struct {
+	Name                         string
+	Namespace                    string
+	Labels                       k8s.Labels
+	Annotations                  map[string]string
+	AutomountServiceAccountToken *bool
+}
+
+// New creates a new ServiceAccount for the pods of the Samtest.
+func (s *ServiceAccount) New(crd *cachev1alpha1.Samtest) Resource {
+	serviceAccount := &ServiceAccount{
+		Name:      crd.Name,
+		Namespace: crd.Namespace,
+		Labels:    k8s.CreateLabels(crd.Name),
+	}
+
+	if spec := crd.Spec.ServiceAccount; spec != nil {
+		serviceAccount.Annotations = maps.Clone(spec.Annotations)
+		serviceAccount.AutomountServiceAccountToken = spec.AutomountServiceAccountToken
+	}
+
+	return serviceAccount
+}
+
+// Returns the resource kind.
+func (s *ServiceAccount) Kind() string {
+	return "ServiceAccount"
+}
+
+// Creates a new ServiceAccount Kubernetes object.
+func (s *ServiceAccount) Generate() client.Object {
+	return &corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       s.Kind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.Name,
+			Namespace:   s.Namespace,
+			Labels:      s.Labels,
+			Annotations: s.Annotations,
+		},
+		AutomountServiceAccountToken: s.AutomountServiceAccountToken,
+	}
+}
+
+// Checks whether the found ServiceAccount carries the desired annotations and
+// token mount setting. Annotations added by others are ignored.
+func (s *ServiceAccount) IsEqual(found client.Object) bool {
+	foundServiceAccount, ok := found.(*corev1.ServiceAccount)
+	if !ok {
+		return false
+	}
+
+	return ptr.Equal(s.AutomountServiceAccountToken, foundServiceAccount.AutomountServiceAccountToken) &&
+		hasAnnotations(s.Annotations, found)
+}
+
+// A ServiceAccount is healthy once it exists.
+func (s *ServiceAccount) IsHealthy(_ context.Context, _ client.Reader, _ client.Object) (bool, string, error) {
+	return true, "", nil
+}
+
+// The ServiceAccount reports nothing on the Samtest status.
+func (s *ServiceAccount) ReportStatus(_ client.Object, _ *cachev1alpha1.SamtestStatus) {}
+
+// GeneratesServiceAccount reports whether a ServiceAccount is generated for
+// the Samtest, rather than an existing one being referenced.
+func GeneratesServiceAccount(crd *cachev1alpha1.Samtest) bool {
+	return crd.Spec.ServiceAccount == nil || crd.Spec.ServiceAccount.Name == ""
+}
+
+// Returns the name of the ServiceAccount the pods of the Samtest run as.
+func serviceAccountName(crd *cachev1alpha1.Samtest) string {
+	if GeneratesServiceAccount(crd) {
+		return crd.Name
+	}
+	return crd.Spec.ServiceAccount.Name
+}
+
+// Returns whether the pods of the Samtest mount the API token of their
+// ServiceAccount, left unset to defer to the ServiceAccount.
+func automountServiceAccountToken(crd *cachev1alpha1.Samtest) *bool {
+	if crd.Spec.ServiceAccount == nil || crd.Spec.ServiceAccount.AutomountServiceAccountToken == nil {
+		return nil
+	}
+	automount := *crd.Spec.ServiceAccount.AutomountServiceAccountToken
+	return &automount
+}
@@ -0,0 +1,54 @@
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	cachev1alpha1 "github.com/s-humphreys/go-operator-sdk/api/v1alpha1"
+)
+
+// Returns the volumes of the pods, with the PersistentVolumeClaim of the
+// Samtest last when persistence is configured.
+func podVolumes(crd *cachev1alpha1.Samtest) []corev1.Volume {
+	var volumes []corev1.Volume
+	for _, volume := range crd.Spec.Volumes {
+		volumes = append(volumes, corev1.Volume{
+			Name: volume.Name,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: volume.ConfigMap.DeepCopy(),
+				Secret:    volume.Secret.DeepCopy(),
+				EmptyDir:  volume.EmptyDir.DeepCopy(),
+				Projected: volume.Projected.DeepCopy(),
+				CSI:       volume.CSI.DeepCopy(),
+			},
+		})
+	}
+
+	if crd.Spec.Persistence != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: persistenceVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: crd.Name,
+				},
+			},
+		})
+	}
+	return volumes
+}
+
+// Returns the volume mounts of the main container, with the
+// PersistentVolumeClaim of the Samtest last when persistence is configured.
+func containerVolumeMounts(crd *cachev1alpha1.Samtest) []corev1.VolumeMount {
+	var mounts []corev1.VolumeMount
+	for _, mount := range crd.Spec.VolumeMounts {
+		mounts = append(mounts, *mount.DeepCopy())
+	}
+
+	if crd.Spec.Persistence != nil {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      persistenceVolumeName,
+			MountPath: crd.Spec.Persistence.MountPath,
+		})
+	}
+	return mounts
+}